@@ -0,0 +1,317 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spmfte/tuiodo/model"
+	"github.com/spmfte/tuiodo/storage"
+)
+
+// runCommand runs a command given after the options, such as
+// "tuiodo backups list", and returns the exit code
+func runCommand(args []string) int {
+	switch args[0] {
+	case "backups":
+		return runBackupsCommand(args[1:])
+	case "log":
+		return runLogCommand(args[1:])
+	case "list":
+		return runListCommand(args[1:])
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: unknown command %q (see tuiodo --help)\n", args[0])
+	return 2
+}
+
+// runBackupsCommand lists, compares and restores backups
+func runBackupsCommand(args []string) int {
+	sub := "list"
+	if len(args) > 0 {
+		sub, args = args[0], args[1:]
+	}
+
+	var err error
+	switch {
+	case sub == "list" && len(args) == 0:
+		err = listBackups()
+	case sub == "diff" && len(args) == 1:
+		err = diffBackup(args[0])
+	case sub == "restore" && len(args) == 1:
+		err = restoreBackup(args[0])
+	case sub == "pick" && len(args) >= 2:
+		err = pickFromBackup(args[0], args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, `Usage:
+  tuiodo backups [list]                 List backups with task counts and changes since
+  tuiodo backups diff <backup>          Show tasks added, removed and completed since a backup
+  tuiodo backups restore <backup>       Restore a backup (the current tasks are backed up first)
+  tuiodo backups pick <backup> <id>...  Restore single tasks from a backup by ID`)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// listBackups prints the backups, newest first
+func listBackups() error {
+	backups, err := storage.ListBackups()
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Printf("No backups in %s\n", storage.GetBackupDirectory())
+		return nil
+	}
+
+	current := storage.LoadTasks()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BACKUP\tTIME\tTASKS\tDONE\tADDED\tREMOVED\tCOMPLETED")
+	for _, b := range backups {
+		done := 0
+		for _, task := range b.Tasks {
+			if task.Done {
+				done++
+			}
+		}
+		diff := model.DiffTasks(b.Tasks, current)
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\n",
+			b.Name, b.Time.Format("2006-01-02 15:04:05"), len(b.Tasks), done,
+			len(diff.Added), len(diff.Removed), len(diff.Completed))
+	}
+	return w.Flush()
+}
+
+// diffBackup prints how the current tasks differ from a backup
+func diffBackup(name string) error {
+	backup, err := storage.LoadBackup(name)
+	if err != nil {
+		return err
+	}
+
+	diff := model.DiffTasks(backup.Tasks, storage.LoadTasks())
+	if diff.Empty() {
+		fmt.Println("No changes since", name)
+		return nil
+	}
+
+	printTasks := func(prefix string, tasks []model.Task) {
+		for _, task := range tasks {
+			fmt.Printf("%s %s  %s\n", prefix, task.ID, task.Description)
+		}
+	}
+	printTasks("+", diff.Added)
+	printTasks("-", diff.Removed)
+	printTasks("✓", diff.Completed)
+	printTasks("○", diff.Reopened)
+	printTasks("~", diff.Changed)
+	return nil
+}
+
+// restoreBackup replaces the current tasks with a backup's
+func restoreBackup(name string) error {
+	tasks, err := storage.RestoreBackup(name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d tasks from %s; the previous tasks were backed up\n", len(tasks), name)
+	return nil
+}
+
+// pickFromBackup restores the tasks with the given IDs from a backup
+func pickFromBackup(name string, ids []string) error {
+	backup, err := storage.LoadBackup(name)
+	if err != nil {
+		return err
+	}
+
+	m := model.NewModel(storage.LoadTasks())
+	for _, id := range ids {
+		found := false
+		for _, task := range backup.Tasks {
+			if task.ID == id {
+				m.RestoreTask(task)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no task %s in %s", id, name)
+		}
+	}
+
+	if err := storage.SaveTasks(m.Tasks); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d task(s) from %s\n", len(ids), name)
+	return nil
+}
+
+// runLogCommand prints the activity log, filtered by task, category or date
+func runLogCommand(args []string) int {
+	flags := flag.NewFlagSet("log", flag.ContinueOnError)
+	var filter storage.ActivityFilter
+	var since, until string
+	flags.StringVar(&filter.TaskID, "task", "", "Only changes to the task with this ID")
+	flags.StringVar(&filter.Category, "category", "", "Only changes to tasks in this category")
+	flags.StringVar(&since, "since", "", "Only changes on or after this date, e.g. 2025-03-01 or today")
+	flags.StringVar(&until, "until", "", "Only changes on or before this date")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:\n  tuiodo log [--task <id>] [--category <name>] [--since <date>] [--until <date>]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		if err == nil {
+			flags.Usage()
+		}
+		return 2
+	}
+
+	var err error
+	if since != "" {
+		filter.Since, err = logDate(since)
+	}
+	if until != "" && err == nil {
+		// The whole of the last day is included
+		filter.Until, err = logDate(until)
+		filter.Until = filter.Until.AddDate(0, 0, 1)
+	}
+	if err == nil {
+		err = printActivity(filter)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// logDate reads a date given to tuiodo log, in any form @due accepts
+func logDate(value string) (time.Time, error) {
+	date, err := model.ResolveDue(value, time.Now())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(model.DateLayout, date[:len(model.DateLayout)], time.Local)
+}
+
+// printActivity prints the events in the activity log selected by filter
+func printActivity(filter storage.ActivityFilter) error {
+	events, err := storage.ReadActivity(filter)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		fmt.Printf("No matching changes in %s\n", storage.ActivityLogPath())
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tACTION\tTASK\tDESCRIPTION\tCATEGORY\tBY")
+	for _, event := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			event.Time.Local().Format("2006-01-02 15:04:05"), describeAction(event),
+			event.TaskID, event.Description, event.Category, event.Actor)
+	}
+	return w.Flush()
+}
+
+// describeAction names what an event did, with the old and new priority
+// when it was re-prioritised
+func describeAction(event model.Event) string {
+	if event.Action == model.ActionPriority {
+		from, to := event.From, event.To
+		if from == "" {
+			from = "none"
+		}
+		if to == "" {
+			to = "none"
+		}
+		return fmt.Sprintf("%s %s→%s", event.Action, from, to)
+	}
+	return event.Action
+}
+
+// runListCommand prints the tasks matching a query, for scripts
+func runListCommand(args []string) int {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	sort := flags.String("sort", "", "Sort by priority, created, category or due")
+	idsOnly := flags.Bool("ids", false, "Print only the IDs of the matching tasks, one per line")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, `Usage:
+  tuiodo list [--sort <field>] [--ids] [query]
+
+The query takes the same expressions as the filter bar (f), e.g.
+  tuiodo list 'priority>=high tag:backend due<7d -status:blocked'`)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	text := strings.Join(flags.Args(), " ")
+	query, err := model.ParseQuery(text)
+	if err != nil {
+		var queryErr *model.QueryError
+		if errors.As(err, &queryErr) {
+			fmt.Fprintf(os.Stderr, "Error: %s\n  %s\n  %s^\n", queryErr.Message, text, strings.Repeat(" ", utf8.RuneCountInString(text[:queryErr.Offset])))
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		return 2
+	}
+
+	m := model.NewModel(storage.LoadTasks())
+	switch *sort {
+	case "":
+	case "priority", "created", "category", "due":
+		m.SortTasks(model.SortType(*sort))
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid sort field: %s (must be priority, created, category, or due)\n", *sort)
+		return 2
+	}
+
+	tasks := m.QueryTasks(query)
+	if *idsOnly {
+		for _, task := range tasks {
+			fmt.Println(task.ID)
+		}
+		return 0
+	}
+	if len(tasks) == 0 {
+		fmt.Fprintln(os.Stderr, "No matching tasks")
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tPRIORITY\tDUE\tCATEGORY\tDESCRIPTION")
+	for _, task := range tasks {
+		status := "pending"
+		switch {
+		case task.Archived:
+			status = "archived"
+		case task.Done:
+			status = "done"
+		case m.IsBlocked(task.ID):
+			status = "blocked"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			task.ID, status, task.Priority, task.Metadata["due"], task.Category, task.Description)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
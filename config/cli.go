@@ -15,6 +15,7 @@ type CLIFlags struct {
 	PrintConfig         bool
 	CreateDefaultConfig bool
 	StoragePath         string
+	TreeRoot            string // Show every task file below this directory
 	TasksPerPage        int
 	ShowHelp            bool
 	ShowVersion         bool
@@ -28,6 +29,7 @@ type CLIFlags struct {
 	Category            string
 	Sort                string
 	View                string
+	Args                []string // Command and its arguments, e.g. "backups list"
 }
 
 // ParseFlags parses command-line flags
@@ -44,6 +46,8 @@ func ParseFlags() CLIFlags {
 	flag.StringVar(&flags.StoragePath, "storage", "", "Path to storage file (overrides config)")
 	flag.StringVar(&flags.StoragePath, "s", "", "Path to storage file (shorthand)")
 
+	flag.StringVar(&flags.TreeRoot, "tree", "", "Show the tasks of every task file below a directory")
+
 	flag.IntVar(&flags.TasksPerPage, "tasks-per-page", 0, "Number of tasks per page (overrides config)")
 	flag.IntVar(&flags.TasksPerPage, "t", 0, "Number of tasks per page (shorthand)")
 
@@ -58,13 +62,13 @@ func ParseFlags() CLIFlags {
 	flag.BoolVar(&flags.NoColor, "no-color", false, "Disable color output")
 
 	flag.StringVar(&flags.BackupDir, "backup-dir", "", "Set backup directory (overrides config)")
-	flag.IntVar(&flags.MaxBackups, "max-backups", 5, "Set maximum number of backups (overrides config)")
+	flag.IntVar(&flags.MaxBackups, "max-backups", 0, "Keep only the newest N backups, using the count retention policy (overrides config)")
 	flag.BoolVar(&flags.NoAutoSave, "no-auto-save", false, "Disable auto-save feature")
 	flag.BoolVar(&flags.NoBackup, "no-backup", false, "Disable backup on save")
 
 	flag.StringVar(&flags.Category, "category", "", "Start with specific category filter")
-	flag.StringVar(&flags.Sort, "sort", "", "Initial sort field (priority|created|category)")
-	flag.StringVar(&flags.View, "view", "", "Initial view (all|pending|completed)")
+	flag.StringVar(&flags.Sort, "sort", "", "Initial sort field (priority|created|category|due)")
+	flag.StringVar(&flags.View, "view", "", "Initial view (all|today|overdue|upcoming|pending|waiting|completed)")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of tuiodo:\n")
@@ -75,6 +79,7 @@ func ParseFlags() CLIFlags {
 		fmt.Fprintf(os.Stderr, "  tuiodo --config ~/.config/tuiodo/my-config.yaml\n")
 		fmt.Fprintf(os.Stderr, "  tuiodo --create-default-config\n")
 		fmt.Fprintf(os.Stderr, "  tuiodo --storage ~/my-tasks.md\n")
+		fmt.Fprintf(os.Stderr, "  tuiodo --tree ~/src/monorepo\n")
 		fmt.Fprintf(os.Stderr, "  tuiodo --category Work\n")
 		fmt.Fprintf(os.Stderr, "  tuiodo --sort priority\n")
 		fmt.Fprintf(os.Stderr, "  tuiodo --view pending\n")
@@ -83,6 +88,7 @@ func ParseFlags() CLIFlags {
 
 	// Parse flags
 	flag.Parse()
+	flags.Args = flag.Args()
 
 	return flags
 }
@@ -157,8 +163,11 @@ func HandleConfigFlags(flags CLIFlags) (Config, bool) {
 		config.Storage.BackupDirectory = flags.BackupDir
 	}
 
+	// Keeping a number of backups means counting them, whatever the config's
+	// retention policy
 	if flags.MaxBackups > 0 {
 		config.Storage.MaxBackups = flags.MaxBackups
+		config.Storage.Retention.Policy = "count"
 	}
 
 	return config, false
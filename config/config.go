@@ -96,11 +96,27 @@ type KeybindingsConfig struct {
 
 // StorageConfig contains storage-related settings
 type StorageConfig struct {
+	Backend         string `yaml:"backend"` // Storage format: markdown or jsonl
 	FilePath        string `yaml:"file_path"`
 	BackupDirectory string `yaml:"backup_directory"`
 	AutoSave        bool   `yaml:"auto_save"`
 	BackupOnSave    bool   `yaml:"backup_on_save"`
-	MaxBackups      int    `yaml:"max_backups"`
+	MaxBackups      int    `yaml:"max_backups"` // Backups kept when the retention policy is "count"
+	CompressBackups bool   `yaml:"compress_backups"`
+
+	Retention RetentionConfig `yaml:"retention"`
+}
+
+// RetentionConfig decides which backups are kept. The "tiered" policy keeps
+// every backup for keep_all, then the newest per hour for hourly, per day for
+// daily and per week for weekly ("forever" never removes weekly backups).
+// The "count" policy keeps the newest max_backups.
+type RetentionConfig struct {
+	Policy  string `yaml:"policy"` // tiered or count
+	KeepAll string `yaml:"keep_all"`
+	Hourly  string `yaml:"hourly"`
+	Daily   string `yaml:"daily"`
+	Weekly  string `yaml:"weekly"`
 }
 
 // DisplayConfig contains display-related settings
@@ -112,7 +128,7 @@ type DisplayConfig struct {
 type FilesConfig struct {
 	GlobalTodoFile    string   `yaml:"global_todo_file"`    // Path to global todo file
 	DirectoryTodoFile string   `yaml:"directory_todo_file"` // Name of directory-specific todo files
-	ActiveFile        string   `yaml:"active_file"`         // Currently active todo file
+	ActiveFile        string   `yaml:"active_file"`         // Active todo file: project or global
 	ExcludeDirs       []string `yaml:"exclude_dirs"`        // Directories to exclude from todo file search
 }
 
@@ -201,11 +217,19 @@ func DefaultConfig() Config {
 			ClearStatus:     5,
 		},
 		Storage: StorageConfig{
+			Backend:         "markdown",
 			FilePath:        filepath.Join(homeDir, "TODO.md"),
 			BackupDirectory: filepath.Join(configDir, "backups"),
 			AutoSave:        true,
 			BackupOnSave:    true,
 			MaxBackups:      5,
+			Retention: RetentionConfig{
+				Policy:  "tiered",
+				KeepAll: "1h",
+				Hourly:  "24h",
+				Daily:   "30d",
+				Weekly:  "forever",
+			},
 		},
 		UI: UIConfig{
 			ShowHeader:      true,
@@ -403,15 +427,38 @@ func mergeWithDefaults(config, defaults Config) Config {
 	}
 
 	// Merge Storage section
+	if config.Storage.Backend == "" {
+		config.Storage.Backend = defaults.Storage.Backend
+	}
 	if config.Storage.FilePath == "" {
 		config.Storage.FilePath = defaults.Storage.FilePath
 	}
 	if config.Storage.BackupDirectory == "" {
 		config.Storage.BackupDirectory = defaults.Storage.BackupDirectory
 	}
+	// A config setting max_backups without a retention policy, as written
+	// before there were policies, keeps only that many backups
+	if config.Storage.Retention.Policy == "" && config.Storage.MaxBackups > 0 {
+		config.Storage.Retention.Policy = "count"
+	}
 	if config.Storage.MaxBackups == 0 {
 		config.Storage.MaxBackups = defaults.Storage.MaxBackups
 	}
+	if config.Storage.Retention.Policy == "" {
+		config.Storage.Retention.Policy = defaults.Storage.Retention.Policy
+	}
+	if config.Storage.Retention.KeepAll == "" {
+		config.Storage.Retention.KeepAll = defaults.Storage.Retention.KeepAll
+	}
+	if config.Storage.Retention.Hourly == "" {
+		config.Storage.Retention.Hourly = defaults.Storage.Retention.Hourly
+	}
+	if config.Storage.Retention.Daily == "" {
+		config.Storage.Retention.Daily = defaults.Storage.Retention.Daily
+	}
+	if config.Storage.Retention.Weekly == "" {
+		config.Storage.Retention.Weekly = defaults.Storage.Retention.Weekly
+	}
 
 	// Merge Colors section
 	if config.Colors.Theme == "" {
@@ -544,6 +591,53 @@ func SaveDefaultConfig(path string) error {
 	return SaveConfig(DefaultConfig(), path)
 }
 
+// SaveActiveFile remembers the active task file in the config file at path.
+// Only files.active_file is changed; the rest of the file, comments
+// included, is kept as it is.
+func SaveActiveFile(path, name string) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	files := mappingValue(doc.Content[0], "files", yaml.MappingNode)
+	active := mappingValue(files, "active_file", yaml.ScalarNode)
+	active.SetString(name)
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in a YAML mapping, adding an empty
+// one of the given kind if the key is missing
+func mappingValue(mapping *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	value := &yaml.Node{Kind: kind}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
+
 // GetConfigFilePath returns the path to the configuration file
 // It will create the directory if it doesn't exist
 func GetConfigFilePath() (string, error) {
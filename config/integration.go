@@ -339,13 +339,15 @@ type KeyBindings struct {
 // ValidateFlags validates the provided flags
 func ValidateFlags(flags CLIFlags) error {
 	// Validate sort field
-	if flags.Sort != "" && flags.Sort != "priority" && flags.Sort != "created" && flags.Sort != "category" {
-		return fmt.Errorf("invalid sort field: %s (must be priority, created, or category)", flags.Sort)
+	if flags.Sort != "" && flags.Sort != "priority" && flags.Sort != "created" && flags.Sort != "category" && flags.Sort != "due" {
+		return fmt.Errorf("invalid sort field: %s (must be priority, created, category, or due)", flags.Sort)
 	}
 
 	// Validate view type
-	if flags.View != "" && flags.View != "all" && flags.View != "pending" && flags.View != "completed" {
-		return fmt.Errorf("invalid view type: %s (must be all, pending, or completed)", flags.View)
+	switch flags.View {
+	case "", "all", "today", "overdue", "upcoming", "pending", "waiting", "completed":
+	default:
+		return fmt.Errorf("invalid view type: %s (must be all, today, overdue, upcoming, pending, waiting, or completed)", flags.View)
 	}
 
 	// Validate tasks per page
@@ -358,5 +360,10 @@ func ValidateFlags(flags CLIFlags) error {
 		return fmt.Errorf("max backups must be >= 0")
 	}
 
+	// A tree is made of the files found below it, not a single storage file
+	if flags.TreeRoot != "" && flags.StoragePath != "" {
+		return fmt.Errorf("--tree and --storage can't be used together")
+	}
+
 	return nil
 }
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spmfte/tuiodo/model"
+	"github.com/spmfte/tuiodo/storage"
+)
+
+// openBackups lists the backups and opens the backup browser
+func openBackups(m *model.Model) {
+	backups, err := storage.ListBackups()
+	if err != nil {
+		m.SetStatus(fmt.Sprintf("Error listing backups: %v", err))
+		return
+	}
+	if len(backups) == 0 {
+		m.SetStatus(fmt.Sprintf("No backups in %s", storage.GetBackupDirectory()))
+		return
+	}
+
+	m.Backups = backups
+	m.BackupCursor = 0
+	m.BackupOpen = false
+	m.BackupTaskIdx = 0
+	m.RestoreConfirm = false
+	m.BackupsVisible = true
+}
+
+// handleBackupMode processes keyboard input in the backup browser
+func handleBackupMode(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
+	// Any key other than 'r' cancels a pending restore
+	if m.RestoreConfirm && msg.String() != "r" {
+		m.RestoreConfirm = false
+		m.SetStatus("Restore cancelled")
+		return m, nil
+	}
+
+	backup := m.Backups[m.BackupCursor]
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q", "B":
+		if m.BackupOpen {
+			m.BackupOpen = false
+		} else {
+			m.BackupsVisible = false
+			m.Backups = nil
+		}
+	case "left", "h":
+		m.BackupOpen = false
+	case "enter", "right", "l": // Show the backup's tasks
+		m.BackupOpen = true
+		m.BackupTaskIdx = 0
+	case "up", "k":
+		if m.BackupOpen {
+			if m.BackupTaskIdx > 0 {
+				m.BackupTaskIdx--
+			}
+		} else if m.BackupCursor > 0 {
+			m.BackupCursor--
+		}
+	case "down", "j":
+		if m.BackupOpen {
+			if m.BackupTaskIdx < len(backup.Tasks)-1 {
+				m.BackupTaskIdx++
+			}
+		} else if m.BackupCursor < len(m.Backups)-1 {
+			m.BackupCursor++
+		}
+	case "p": // Cherry-pick the selected task
+		if m.BackupOpen && m.BackupTaskIdx < len(backup.Tasks) {
+			m.RestoreTask(backup.Tasks[m.BackupTaskIdx])
+			m.SetStatus(fmt.Sprintf("Task restored from %s", backup.Name))
+			saveTasks(&m)
+		}
+	case "r": // Restore the whole backup
+		if !m.RestoreConfirm {
+			m.RestoreConfirm = true
+			prompt := fmt.Sprintf("Press 'r' again to restore %s (current tasks are backed up first)", backup.Name)
+			if m.Dirty {
+				prompt += "; unsaved changes will be lost"
+			}
+			m.SetStatus(prompt)
+			return m, nil
+		}
+
+		m.RestoreConfirm = false
+		tasks, err := storage.RestoreBackup(backup.Name)
+		if err != nil {
+			m.SetStatus(fmt.Sprintf("Error restoring %s: %v", backup.Name, err))
+			return m, nil
+		}
+
+		m.ReplaceTasks(tasks)
+		m.Dirty = false
+		m.BackupsVisible = false
+		m.Backups = nil
+		m.SetStatus(fmt.Sprintf("Restored %s; the previous tasks were backed up", backup.Name))
+	}
+
+	return m, nil
+}
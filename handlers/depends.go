@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+// CheckDependencies warns in the status bar about cycles among the tasks'
+// @blocked-by and @after relations, which keep every task on them blocked.
+// It returns false if there are any.
+func CheckDependencies(m *model.Model) bool {
+	cycles := m.DependencyCycles()
+	if len(cycles) == 0 {
+		return true
+	}
+	status := "Warning: dependency cycle " + strings.Join(cycles[0], " → ")
+	if len(cycles) > 1 {
+		status += fmt.Sprintf(" (and %d more)", len(cycles)-1)
+	}
+	m.SetStatus(status)
+	return false
+}
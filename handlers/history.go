@@ -0,0 +1,33 @@
+package handlers
+
+import "github.com/spmfte/tuiodo/model"
+
+// undo reverts the last change to the tasks and saves
+func undo(m *model.Model) {
+	if !m.CanUndo() {
+		m.SetStatus("Nothing to undo")
+		return
+	}
+	label, ok := m.Undo()
+	if !ok {
+		m.SetStatus("Can't undo: the tasks were changed on disk since")
+		return
+	}
+	m.SetStatus("Undone: " + label)
+	saveTasks(m)
+}
+
+// redo applies the last undone change again and saves
+func redo(m *model.Model) {
+	if !m.CanRedo() {
+		m.SetStatus("Nothing to redo")
+		return
+	}
+	label, ok := m.Redo()
+	if !ok {
+		m.SetStatus("Can't redo: the tasks were changed on disk since")
+		return
+	}
+	m.SetStatus("Redone: " + label)
+	saveTasks(m)
+}
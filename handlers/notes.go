@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spmfte/tuiodo/model"
+)
+
+// handleNotesMode processes keyboard input in the notes editor
+func handleNotesMode(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
+	editor := m.NotesEditor
+
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace:
+		editor.Insert(string(msg.Runes))
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "ctrl+s": // Save the notes
+		if m.SaveNotes() {
+			m.SetStatus("Notes saved")
+			saveTasks(&m)
+		} else {
+			m.SetStatus("Error: the task no longer exists")
+		}
+	case "esc": // Discard the changes
+		m.NotesEditor = nil
+		m.SetStatus("Notes not changed")
+	case "enter":
+		editor.Newline()
+	case "tab":
+		editor.Insert("  ")
+	case "backspace":
+		editor.Backspace()
+	case "delete":
+		editor.Delete()
+	case "up":
+		editor.Move(-1, 0)
+	case "down":
+		editor.Move(1, 0)
+	case "left":
+		editor.Move(0, -1)
+	case "right":
+		editor.Move(0, 1)
+	case "home", "ctrl+a":
+		editor.Home()
+	case "end", "ctrl+e":
+		editor.End()
+	}
+
+	return m, nil
+}
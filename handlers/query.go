@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spmfte/tuiodo/model"
+)
+
+// openQuery opens the query bar with the current query to edit
+func openQuery(m *model.Model) {
+	m.EditingQuery = true
+	m.InputMode = true
+	m.Input = ""
+	if m.Query != nil {
+		m.Input = m.Query.Text
+	}
+	m.InputCursor = len(m.Input)
+}
+
+// applyQueryInput filters the list with the query typed into the query bar.
+// A query that can't be parsed keeps the bar open; the error is shown below
+// it as it is typed.
+func applyQueryInput(m model.Model) (model.Model, tea.Cmd) {
+	if err := m.SetQuery(m.Input); err != nil {
+		m.SetStatus(fmt.Sprintf("Error: %v", err))
+		return m, nil
+	}
+	m.EditingQuery = false
+	m.InputMode = false
+	m.Input = ""
+	m.InputCursor = 0
+
+	if m.Query == nil {
+		m.SetStatus("Query cleared")
+	} else {
+		m.SetStatus(fmt.Sprintf("%d task(s) match %s (esc clears)", len(m.GetFilteredTasks()), m.Query.Text))
+	}
+	return m, nil
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spmfte/tuiodo/model"
+	"github.com/spmfte/tuiodo/storage"
+)
+
+// FileChangedMsg is sent when the task file was modified outside tuiodo
+type FileChangedMsg struct {
+	changes <-chan struct{}
+}
+
+// WatchTaskFile returns a command that waits for the next change reported on
+// changes, as returned by storage.Watch
+func WatchTaskFile(changes <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		if _, ok := <-changes; !ok {
+			return nil
+		}
+		return FileChangedMsg{changes: changes}
+	}
+}
+
+// reloadTasks picks up outside changes to the task file. Unsaved changes are
+// merged with them rather than discarded.
+func reloadTasks(m *model.Model) {
+	fileName := filepath.Base(storage.GetStoragePath())
+
+	if !m.Dirty {
+		m.ReplaceTasks(storage.LoadTasks())
+		m.SetStatus(fmt.Sprintf("%s changed on disk, reloaded", fileName))
+		CheckDependencies(m)
+		return
+	}
+
+	if !mergeFromDisk(m) {
+		return
+	}
+	m.SetStatus(fmt.Sprintf("%s changed on disk, merged with unsaved changes", fileName))
+	CheckDependencies(m)
+
+	if storage.IsAutoSaveEnabled() {
+		writeTasks(m)
+	}
+}
+
+// mergeFromDisk reloads the task file and merges it with the tasks in memory.
+// It returns false if there are conflicts left for the user to resolve.
+func mergeFromDisk(m *model.Model) bool {
+	base := storage.LoadedTasks()
+	theirs := storage.LoadTasks()
+
+	merged, conflicts := model.MergeTasks(base, theirs, m.Tasks)
+	m.ReplaceTasks(merged)
+	m.ClearHistory() // Undoing would take back the outside changes too
+	m.Conflicts = append(m.Conflicts, conflicts...)
+
+	if len(m.Conflicts) > 0 {
+		m.Dirty = true
+		m.SetStatus(fmt.Sprintf("%d conflicting change(s) with %s", len(m.Conflicts), filepath.Base(storage.GetStoragePath())))
+		return false
+	}
+	return true
+}
+
+// resolveConflict settles the first pending conflict. Once none are left the
+// merged tasks are saved.
+func resolveConflict(m *model.Model, takeTheirs bool) {
+	if len(m.Conflicts) == 0 {
+		return
+	}
+
+	m.ResolveConflict(m.Conflicts[0], takeTheirs)
+	m.Conflicts = m.Conflicts[1:]
+
+	if len(m.Conflicts) == 0 {
+		m.Conflicts = nil
+		m.SetStatus("All conflicts resolved")
+		saveTasks(m)
+	}
+}
+
+// saveTasks writes the tasks to disk if auto-save is on, and otherwise marks
+// them as unsaved until the user saves with ctrl+s
+func saveTasks(m *model.Model) {
+	if !storage.IsAutoSaveEnabled() || len(m.Conflicts) > 0 {
+		m.Dirty = true
+		return
+	}
+	writeTasks(m)
+}
+
+// writeTasks writes the tasks to disk. If the file was changed outside tuiodo
+// since it was loaded, those changes are merged in first. Nothing is written
+// while there are unresolved conflicts.
+func writeTasks(m *model.Model) {
+	if len(m.Conflicts) > 0 {
+		m.SetStatus("Resolve the conflicts with the file on disk before saving")
+		return
+	}
+
+	err := storage.SaveTasks(m.Tasks)
+	if errors.Is(err, storage.ErrFileChanged) {
+		if !mergeFromDisk(m) {
+			return
+		}
+		m.SetStatus("Merged outside changes before saving")
+		err = storage.SaveTasks(m.Tasks)
+	}
+
+	if err != nil {
+		m.Dirty = true
+		m.SetStatus(fmt.Sprintf("Error saving tasks: %v", err))
+		return
+	}
+	m.Dirty = false
+	loadHistory(m) // The save added to it
+}
+
+// loadHistory reads the activity log of the expanded task, if any
+func loadHistory(m *model.Model) {
+	m.ExpandedHistory = nil
+	if !m.TaskExpanded {
+		return
+	}
+	events, err := storage.ReadActivity(storage.ActivityFilter{TaskID: m.ExpandedTaskID})
+	if err == nil {
+		m.ExpandedHistory = events
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spmfte/tuiodo/model"
+)
+
+// handleSearchMode narrows the list as the search is typed
+func handleSearchMode(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "enter": // Keep the search and go back to the list
+		m.SearchMode = false
+		reportSearch(&m)
+	case "esc":
+		m.SearchMode = false
+		m.SetSearch("")
+		m.SetStatus("Search cleared")
+	case "backspace":
+		if runes := []rune(m.Search); len(runes) > 0 {
+			m.SetSearch(string(runes[:len(runes)-1]))
+		}
+	case "ctrl+u":
+		m.SetSearch("")
+	case "up":
+		m.MoveCursorUp()
+	case "down":
+		m.MoveCursorDown()
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			m.SetSearch(m.Search + string(msg.Runes))
+		}
+	}
+	return m, nil
+}
+
+// jumpToMatch moves to the next (step 1) or previous (step -1) match
+func jumpToMatch(m *model.Model, step int) {
+	if !m.JumpToMatch(step) {
+		m.SetStatus(fmt.Sprintf("No tasks match %q", m.Search))
+	}
+}
+
+// reportSearch shows how many tasks match the search and how to move
+// between them
+func reportSearch(m *model.Model) {
+	if m.Search == "" {
+		m.SetStatus("Search cleared")
+		return
+	}
+	m.SetStatus(fmt.Sprintf("%d task(s) match %q: n/N next/previous • / edit • esc clear", len(m.GetFilteredTasks()), m.Search))
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spmfte/tuiodo/model"
+)
+
+// Bulk actions waiting for the rest of their input, kept in m.BulkPrompt
+const (
+	bulkPriority         = "priority"          // a priority key
+	bulkCategory         = "category"          // a category typed in input mode
+	bulkTags             = "tags"              // +tag and -tag words typed in input mode
+	bulkCompleteBlocked  = "complete-blocked"  // y to complete tasks that are blocked
+	bulkCompleteSubtasks = "complete-subtasks" // y to complete the pending subtasks too
+)
+
+// markTask marks or unmarks the current task and moves on to the next one
+func markTask(m *model.Model) {
+	id := m.CurrentTaskID()
+	if id == "" {
+		return
+	}
+	m.ToggleMark(id)
+	m.MoveCursorDown()
+	reportMarks(m)
+}
+
+// markRange marks the tasks from the one marked last to the current one
+func markRange(m *model.Model) {
+	if id := m.CurrentTaskID(); id != "" {
+		m.MarkRange(id)
+		reportMarks(m)
+	}
+}
+
+// reportMarks shows how many tasks are marked and what can be done to them
+func reportMarks(m *model.Model) {
+	n := len(m.MarkedIDs())
+	if n == 0 {
+		m.SetStatus("No tasks marked")
+		return
+	}
+	m.SetStatus(fmt.Sprintf("%d task(s) marked: space complete • A archive • d delete • p priority • m category • # tags • esc unmark", n))
+}
+
+// handleBulkKey applies the key to every marked task, if it is one of the
+// keys that can. It returns false if the key is left to the task list.
+func handleBulkKey(msg tea.KeyMsg, m *model.Model) bool {
+	ids := m.MarkedIDs()
+	if len(ids) == 0 {
+		return false
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.ClearMarks()
+		m.SetStatus("Marks cleared")
+	case "enter", " ", "space":
+		allDone := true
+		for _, id := range ids {
+			allDone = allDone && m.Tasks[m.TaskIndexByID(id)].Done
+		}
+		if allDone {
+			n := m.SetTasksDone(ids, false)
+			m.ClearMarks()
+			m.SetStatus(fmt.Sprintf("%d task(s) marked as incomplete", n))
+			saveTasks(m)
+			return true
+		}
+		// Tasks waiting for others need confirming before they are completed
+		if blockers := m.BlockersOf(ids); len(blockers) > 0 {
+			m.BulkPrompt = bulkCompleteBlocked
+			m.SetStatus(fmt.Sprintf("Marked tasks are blocked by %s. Complete them anyway? (y/n)", describeTasks(blockers)))
+			return true
+		}
+		completeMarked(m)
+	case "A":
+		n := m.ArchiveTasks(ids, true)
+		m.ClearMarks()
+		m.SetStatus(fmt.Sprintf("%d task(s) archived", n))
+		saveTasks(m)
+	case "U":
+		n := m.ArchiveTasks(ids, false)
+		m.ClearMarks()
+		m.SetStatus(fmt.Sprintf("%d task(s) unarchived", n))
+		saveTasks(m)
+	case "d":
+		if !m.DeleteConfirm {
+			m.DeleteConfirm = true
+			m.SetStatus(fmt.Sprintf("Press 'd' again to delete %d marked task(s), or any other key to cancel", len(ids)))
+			return true
+		}
+		m.DeleteConfirm = false
+		n := m.DeleteTasks(ids)
+		m.ClearMarks()
+		m.SetStatus(fmt.Sprintf("%d task(s) deleted (press 'u' to undo)", n))
+		saveTasks(m)
+	case "p":
+		m.BulkPrompt = bulkPriority
+		m.SetStatus(fmt.Sprintf("Priority for %d marked task(s): c critical • h high • m medium • l low • n none • esc cancel", len(ids)))
+	case "m":
+		m.BulkPrompt = bulkCategory
+		m.InputMode = true
+		m.Input = ""
+		m.InputCursor = 0
+	case "#":
+		m.BulkPrompt = bulkTags
+		m.InputMode = true
+		m.Input = ""
+		m.InputCursor = 0
+	default:
+		return false
+	}
+	return true
+}
+
+// completeMarked completes the marked tasks and offers to complete their
+// pending subtasks too, keeping the marks until that is answered
+func completeMarked(m *model.Model) {
+	ids := m.MarkedIDs()
+	n := m.SetTasksDone(ids, true)
+	if pending := m.PendingSubtasksOf(ids); pending > 0 {
+		m.BulkPrompt = bulkCompleteSubtasks
+		m.SetStatus(fmt.Sprintf("%d task(s) marked as complete. Complete their %d pending subtask(s) too? (y/n)", n, pending))
+	} else {
+		m.ClearMarks()
+		m.SetStatus(fmt.Sprintf("%d task(s) marked as complete", n))
+	}
+	saveTasks(m)
+}
+
+// handleBulkComplete answers the questions asked before and after the
+// marked tasks are completed
+func handleBulkComplete(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
+	prompt := m.BulkPrompt
+	m.BulkPrompt = ""
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+
+	switch prompt {
+	case bulkCompleteBlocked:
+		if msg.String() == "y" {
+			completeMarked(&m)
+		} else {
+			m.SetStatus("Marked tasks left open")
+		}
+	case bulkCompleteSubtasks:
+		ids := m.MarkedIDs()
+		m.ClearMarks()
+		if msg.String() != "y" {
+			m.SetStatus("Marked tasks completed; subtasks left as they are")
+			return m, nil
+		}
+		n := 0
+		for _, id := range ids {
+			n += m.CompleteSubtasks(id)
+		}
+		m.SetStatus(fmt.Sprintf("%d task(s) and %d subtask(s) marked as complete", len(ids), n))
+		saveTasks(&m)
+	}
+	return m, nil
+}
+
+// bulkPriorities maps the keys of the priority prompt to priorities
+var bulkPriorities = map[string]model.Priority{
+	"c": model.PriorityCritical,
+	"h": model.PriorityHigh,
+	"m": model.PriorityMedium,
+	"l": model.PriorityLow,
+	"n": model.PriorityNone,
+}
+
+// handleBulkPriority sets the priority of every marked task to the one
+// picked with the key
+func handleBulkPriority(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
+	m.BulkPrompt = ""
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	priority, ok := bulkPriorities[msg.String()]
+	if !ok {
+		m.SetStatus("Priority unchanged")
+		return m, nil
+	}
+
+	ids := m.MarkedIDs()
+	m.SetPriority(ids, priority)
+	if priority == model.PriorityNone {
+		m.SetStatus(fmt.Sprintf("Priority cleared on %d task(s)", len(ids)))
+	} else {
+		m.SetStatus(fmt.Sprintf("%d task(s) set to %s priority", len(ids), priority))
+	}
+	saveTasks(&m)
+	return m, nil
+}
+
+// bulkInput applies the category or tags typed into the input form to every
+// marked task
+func bulkInput(m model.Model) (model.Model, tea.Cmd) {
+	ids := m.MarkedIDs()
+	input := strings.TrimSpace(m.Input)
+	prompt := m.BulkPrompt
+	m.BulkPrompt = ""
+	m.InputMode = false
+	m.Input = ""
+	m.InputCursor = 0
+
+	switch prompt {
+	case bulkCategory:
+		m.SetCategory(ids, input)
+		if input == "" {
+			m.SetStatus(fmt.Sprintf("Category cleared on %d task(s)", len(ids)))
+		} else {
+			m.SetStatus(fmt.Sprintf("Moved %d task(s) to %s", len(ids), input))
+		}
+	case bulkTags:
+		var add, remove []string
+		for _, word := range strings.Fields(input) {
+			tag, removing := strings.CutPrefix(word, "-")
+			tag = strings.TrimPrefix(strings.TrimPrefix(tag, "+"), "#")
+			switch {
+			case tag == "":
+			case removing:
+				remove = append(remove, tag)
+			default:
+				add = append(add, tag)
+			}
+		}
+		if len(add) == 0 && len(remove) == 0 {
+			return m, nil
+		}
+		m.ChangeTags(ids, add, remove)
+		m.SetStatus(fmt.Sprintf("Tags changed on %d task(s)", len(ids)))
+	}
+	saveTasks(&m)
+	return m, nil
+}
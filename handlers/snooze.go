@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spmfte/tuiodo/model"
+)
+
+// handleSnoozeMode picks how long to snooze the task in m.SnoozeID for
+func handleSnoozeMode(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
+	idx := m.TaskIndexByID(m.SnoozeID)
+	if idx < 0 {
+		m.SnoozeID = ""
+		return m, nil
+	}
+	task := m.Tasks[idx]
+
+	switch msg.String() {
+	case "d", "1":
+		snoozeTask(&m, task.SnoozeDate(1, time.Now()))
+	case "w", "7":
+		snoozeTask(&m, task.SnoozeDate(7, time.Now()))
+	case "c": // Type a date, taken as by @start: in the add form
+		m.InputMode = true
+		m.Input = ""
+		m.InputCursor = 0
+	case "ctrl+c":
+		return m, tea.Quit
+	default:
+		m.SnoozeID = ""
+		m.SetStatus("Snooze cancelled")
+	}
+	return m, nil
+}
+
+// snoozeUntilInput snoozes the task in m.SnoozeID until the date typed into
+// the input form. A date that can't be read keeps the form open.
+func snoozeUntilInput(m model.Model) (model.Model, tea.Cmd) {
+	if strings.TrimSpace(m.Input) == "" {
+		return m, nil
+	}
+	start, err := model.ResolveDue(m.Input, time.Now())
+	if err != nil {
+		m.SetStatus(fmt.Sprintf("Error: %v", err))
+		return m, nil
+	}
+	m.InputMode = false
+	m.Input = ""
+	m.InputCursor = 0
+	snoozeTask(&m, start)
+	return m, nil
+}
+
+// snoozeTask sets the start date of the task in m.SnoozeID, hiding it from
+// the All and Pending tabs until then
+func snoozeTask(m *model.Model, start string) {
+	id := m.SnoozeID
+	m.SnoozeID = ""
+	if m.Snooze(id, start) {
+		m.SetStatus(fmt.Sprintf("Task snoozed until %s; it is in the Waiting tab until then", start))
+		saveTasks(m)
+	}
+}
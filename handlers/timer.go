@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spmfte/tuiodo/model"
+)
+
+// timerRefresh is how often the running timer shown in the status bar is
+// brought up to date
+const timerRefresh = 30 * time.Second
+
+// TimerTickMsg redraws the running timer
+type TimerTickMsg struct{}
+
+// TickTimer returns a command that sends the next TimerTickMsg
+func TickTimer() tea.Cmd {
+	return tea.Tick(timerRefresh, func(time.Time) tea.Msg {
+		return TimerTickMsg{}
+	})
+}
+
+// toggleTimer starts the timer on the current task, or stops it if it is
+// already running there. Starting it stops the timer on any other task.
+func toggleTimer(m *model.Model) {
+	task, ok := m.CurrentTask()
+	if !ok {
+		return
+	}
+	now := time.Now()
+
+	if _, running := task.TimerStarted(); running {
+		session := m.StopTimer(task.ID, now)
+		task = m.Tasks[m.TaskIndexByID(task.ID)]
+		m.SetStatus(fmt.Sprintf("Timer stopped after %s, %s spent in total", model.FormatEffort(session), effortText(task, now)))
+		saveTasks(m)
+		return
+	}
+
+	stopped, _ := m.StartTimer(task.ID, now)
+	if idx := m.TaskIndexByID(stopped); idx >= 0 {
+		m.SetStatus(fmt.Sprintf("Timer started; stopped the one on %q", m.Tasks[idx].Description))
+	} else {
+		m.SetStatus("Timer started (T again to stop it)")
+	}
+	saveTasks(m)
+}
+
+// effortText describes the time spent on a task against its estimate, e.g.
+// "1h10m of 2h"
+func effortText(task model.Task, now time.Time) string {
+	text := model.FormatEffort(task.TimeSpent(now))
+	if estimate, ok := task.Estimate(); ok {
+		text += " of " + model.FormatEffort(estimate)
+	}
+	return text
+}
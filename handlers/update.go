@@ -4,10 +4,10 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spmfte/tuiodo/model"
-	"github.com/spmfte/tuiodo/storage"
 )
 
 // Update processes messages and updates the model accordingly
@@ -18,12 +18,27 @@ func Update(msg tea.Msg, m model.Model) (model.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.UpdateWindowSize(msg.Width, msg.Height)
 		return m, nil
+	case FileChangedMsg:
+		reloadTasks(&m)
+		return m, WatchTaskFile(msg.changes)
+	case TimerTickMsg:
+		return m, TickTimer()
 	}
 	return m, nil
 }
 
-// HandleKeypress processes keyboard input
+// HandleKeypress processes keyboard input. Any change it makes to the tasks
+// is recorded so it can be undone.
 func handleKeypress(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
+	before := m.Checkpoint()
+	m, cmd := dispatchKeypress(msg, m)
+	m.Record(before)
+	return m, cmd
+}
+
+// dispatchKeypress passes a key to the screen or prompt that is open, or
+// handles it in the task list
+func dispatchKeypress(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 	// If help is visible, only respond to help toggle or quit
 	if m.HelpVisible {
 		switch msg.String() {
@@ -46,6 +61,76 @@ func handleKeypress(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// Completing a task with pending subtasks offers to complete them too
+	if m.CompleteSubtasksID != "" {
+		id := m.CompleteSubtasksID
+		m.CompleteSubtasksID = ""
+		if msg.String() == "y" {
+			n := m.CompleteSubtasks(id)
+			m.SetStatus(fmt.Sprintf("Task and %d subtask(s) marked as complete", n))
+			saveTasks(&m)
+			return m, nil
+		}
+		m.SetStatus("Task marked as complete; subtasks left as they are")
+		if msg.String() == "n" || msg.String() == "esc" {
+			return m, nil
+		}
+	}
+
+	// Setting the priority of the marked tasks waits for a priority key
+	if m.BulkPrompt == bulkPriority {
+		return handleBulkPriority(msg, m)
+	}
+
+	// Completing marked tasks asks about blockers and pending subtasks
+	if m.BulkPrompt == bulkCompleteBlocked || m.BulkPrompt == bulkCompleteSubtasks {
+		return handleBulkComplete(msg, m)
+	}
+
+	// Snoozing offers a choice of dates, or a date typed in input mode
+	if m.SnoozeID != "" && !m.InputMode {
+		return handleSnoozeMode(msg, m)
+	}
+
+	// Completing a blocked task has to be confirmed
+	if m.CompleteBlockedID != "" {
+		id := m.CompleteBlockedID
+		m.CompleteBlockedID = ""
+		if idx := m.TaskIndexByID(id); idx >= 0 && msg.String() == "y" {
+			toggleTask(&m, m.Tasks[idx])
+		} else {
+			m.SetStatus("Task left open")
+		}
+		return m, nil
+	}
+
+	// Conflicts with changes on disk have to be resolved first
+	if len(m.Conflicts) > 0 {
+		return handleConflictMode(msg, m)
+	}
+
+	// The backup browser has its own keys
+	if m.BackupsVisible {
+		return handleBackupMode(msg, m)
+	}
+
+	// The notes editor takes all keys until it is closed
+	if m.NotesEditor != nil {
+		return handleNotesMode(msg, m)
+	}
+
+	// Quitting with unsaved changes needs a second 'q'
+	if m.QuitConfirm && msg.String() != "q" {
+		m.QuitConfirm = false
+		m.SetStatus("Quit cancelled")
+		return m, nil
+	}
+
+	// Typing a search narrows the list as it goes
+	if m.SearchMode {
+		return handleSearchMode(msg, m)
+	}
+
 	// If in input mode, handle input-specific keys
 	if m.InputMode {
 		return handleInputMode(msg, m)
@@ -56,10 +141,27 @@ func handleKeypress(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 		return handleEditMode(msg, m)
 	}
 
+	// With tasks marked, the keys for task actions apply to all of them
+	if handleBulkKey(msg, &m) {
+		return m, nil
+	}
+
 	// Normal mode key handling
 	switch msg.String() {
-	case "q", "ctrl+c":
+	case "q":
+		if m.Dirty && !m.QuitConfirm {
+			m.QuitConfirm = true
+			m.SetStatus("Unsaved changes: press ctrl+s to save, or 'q' again to quit without saving")
+			return m, nil
+		}
 		return m, tea.Quit
+	case "ctrl+c":
+		return m, tea.Quit
+	case "ctrl+s": // Save now
+		writeTasks(&m)
+		if !m.Dirty {
+			m.SetStatus("Tasks saved")
+		}
 	case "up", "k":
 		m.MoveCursorUp()
 	case "down", "j":
@@ -68,14 +170,32 @@ func handleKeypress(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 		m.CycleTab()
 	case "a": // Add new task
 		m.InputMode = true
+		m.InputParentID = ""
 		m.Input = ""
 		m.InputCursor = 0
+	case "o": // Add a subtask to the current task
+		if id := m.CurrentTaskID(); id != "" {
+			m.InputMode = true
+			m.InputParentID = id
+			m.Input = ""
+			m.InputCursor = 0
+		}
+	case "z": // Fold or unfold the current task's subtasks
+		if id := m.CurrentTaskID(); id != "" {
+			if m.ToggleCollapsed(id) {
+				if m.IsCollapsed(id) {
+					m.SetStatus("Subtasks hidden")
+				} else {
+					m.SetStatus("Subtasks shown")
+				}
+			} else {
+				m.SetStatus("Task has no subtasks")
+			}
+		}
 	case "e": // Edit current task
-		filteredTasks := m.GetVisibleTasks()
-		if len(filteredTasks) > 0 && m.Cursor < len(filteredTasks) {
-			task := filteredTasks[m.Cursor]
+		if task, ok := m.CurrentTask(); ok {
 			m.EditingTask = true
-			m.EditingTaskIdx = m.Cursor
+			m.EditingTaskID = task.ID
 
 			// Pre-populate input with existing task info
 			if task.Category != "" {
@@ -85,15 +205,23 @@ func handleKeypress(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 			}
 			m.InputCursor = len(m.Input) // Start cursor at the end
 		}
+	case "N": // Previous search match
+		if m.Search != "" {
+			jumpToMatch(&m, -1)
+		}
+	case "E": // Edit the current task's notes
+		if id := m.CurrentTaskID(); id != "" {
+			m.OpenNotesEditor(id)
+		}
 	case "d": // Delete task
 		filteredTasks := m.GetVisibleTasks()
 		if len(filteredTasks) > 0 {
 			if m.DeleteConfirm {
 				// If already in confirmation mode, execute the delete
 				m.DeleteCurrentTask()
-				storage.SaveTasks(m.Tasks)
 				m.SetStatus("Task deleted (press 'u' to undo)")
 				m.DeleteConfirm = false
+				saveTasks(&m)
 
 				// Recalculate pagination after deleting a task
 				m.RecalculatePagination()
@@ -104,54 +232,32 @@ func handleKeypress(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 			}
 		}
 	case "enter", " ", "space": // Toggle task completion - added explicit " " and "space" matches
-		filteredTasks := m.GetVisibleTasks()
-		if len(filteredTasks) > 0 && m.Cursor < len(filteredTasks) {
-			// Get the actual task from the filtered list
-			taskToToggle := filteredTasks[m.Cursor]
-
-			// Find the task's index in the main task list by comparing relevant fields
-			found := false
-			for i, task := range m.Tasks {
-				// Compare only the essential fields that identify a task
-				// Added more robust comparison that doesn't rely as heavily on CreatedAt
-				sameDescription := task.Description == taskToToggle.Description
-				sameCategory := task.Category == taskToToggle.Category
-				sameCreationTime := task.CreatedAt.Equal(taskToToggle.CreatedAt)
-
-				// Use multiple matching criteria for more robust identification
-				if sameDescription && sameCategory && sameCreationTime {
-					// Toggle completion status
-					m.Tasks[i].Done = !m.Tasks[i].Done
-					storage.SaveTasks(m.Tasks)
-
-					// Show status message
-					if m.Tasks[i].Done {
-						m.SetStatus("Task marked as complete")
-					} else {
-						m.SetStatus("Task marked as incomplete")
-					}
-					found = true
-					break
-				}
-			}
-
-			// If task wasn't found in main list, log an error status
-			if !found {
-				m.SetStatus("Error: Could not find task to toggle")
-				// Log more details for debugging
-				log.Printf("Failed to toggle task: %+v", taskToToggle)
+		if task, ok := m.CurrentTask(); ok {
+			// Tasks waiting for others need confirming before they are completed
+			if blockers := m.OpenBlockers(task.ID); !task.Done && len(blockers) > 0 {
+				m.CompleteBlockedID = task.ID
+				m.SetStatus(fmt.Sprintf("Blocked by %s. Complete it anyway? (y/n)", describeTasks(blockers)))
+			} else {
+				toggleTask(&m, task)
 			}
 		}
 	case "c": // Cycle through categories for filtering
 		m.CycleCategory()
+	case "w": // Cycle through "completed within" filters
+		m.CycleCompletedWindow()
+		if m.CompletedWindow == "" {
+			m.SetStatus("Showing all tasks")
+		} else {
+			m.SetStatus("Showing tasks completed " + m.CompletedWindow)
+		}
 	case "p": // Cycle through priorities
-		filteredTasks := m.GetVisibleTasks()
-		if len(filteredTasks) > 0 && m.Cursor < len(filteredTasks) {
+		if id := m.CurrentTaskID(); id != "" {
 			m.CyclePriority()
-			storage.SaveTasks(m.Tasks)
 
 			// Show status based on new priority
-			switch m.Tasks[m.Cursor].Priority {
+			switch m.Tasks[m.TaskIndexByID(id)].Priority {
+			case model.PriorityCritical:
+				m.SetStatus("Task priority set to CRITICAL")
 			case model.PriorityHigh:
 				m.SetStatus("Task priority set to HIGH")
 			case model.PriorityMedium:
@@ -161,8 +267,15 @@ func handleKeypress(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 			default:
 				m.SetStatus("Task priority cleared")
 			}
+			saveTasks(&m)
+		}
+	case "n": // Next search match, or next page
+		if m.Search != "" {
+			jumpToMatch(&m, 1)
+		} else {
+			m.NextPage()
 		}
-	case "right", "l", "n": // Next page
+	case "right", "l": // Next page
 		m.NextPage()
 	case "left", "h", "b": // Previous page
 		m.PrevPage()
@@ -177,55 +290,89 @@ func handleKeypress(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 	case "C": // Sort by category
 		m.SortTasks(model.SortByCategory)
 		m.SetStatus("Sorted by category")
+	case "D": // Sort by due date
+		m.SortTasks(model.SortByDue)
+		m.SetStatus("Sorted by due date")
 	case "x": // Expand/collapse task details
-		filteredTasks := m.GetVisibleTasks()
-		if len(filteredTasks) > 0 && m.Cursor < len(filteredTasks) {
-			if m.TaskExpanded && m.ExpandedTaskIdx == m.Cursor {
+		if id := m.CurrentTaskID(); id != "" {
+			if m.TaskExpanded && m.ExpandedTaskID == id {
 				// Collapse if already expanded
 				m.TaskExpanded = false
 			} else {
 				// Expand the task
 				m.TaskExpanded = true
-				m.ExpandedTaskIdx = m.Cursor
+				m.ExpandedTaskID = id
 			}
+			loadHistory(&m)
 		}
 	case "A": // Archive current task
-		filteredTasks := m.GetVisibleTasks()
-		if len(filteredTasks) > 0 && m.Cursor < len(filteredTasks) {
-			m.ArchiveCurrentTask()
-			storage.SaveTasks(m.Tasks)
+		if m.ArchiveTask(m.CurrentTaskID()) {
 			m.SetStatus("Task archived")
+			saveTasks(&m)
 		}
 	case "U": // Unarchive current task
-		filteredTasks := m.GetVisibleTasks()
-		if len(filteredTasks) > 0 && m.Cursor < len(filteredTasks) {
-			// Find the actual index in the Tasks slice
-			taskToUnarchive := filteredTasks[m.Cursor]
-			var taskIdx int
-			found := false
-
-			for i, task := range m.Tasks {
-				if task.Description == taskToUnarchive.Description &&
-					task.Category == taskToUnarchive.Category &&
-					task.CreatedAt == taskToUnarchive.CreatedAt {
-					taskIdx = i
-					found = true
-					break
-				}
-			}
+		if m.UnarchiveTask(m.CurrentTaskID()) {
+			m.SetStatus("Task unarchived")
+			saveTasks(&m)
+		}
+	case "B": // Browse backups
+		openBackups(&m)
+	case "F": // Switch between the project and global files
+		switchFile(&m)
+	case "M": // Move the current task to the other file
+		moveTask(&m)
+	case "T": // Start or stop the timer on the current task
+		toggleTimer(&m)
+	case "Z": // Snooze the current task
+		if id := m.CurrentTaskID(); id != "" {
+			m.SnoozeID = id
+			m.SetStatus("Snooze until: d tomorrow • w next week • c pick a date • esc cancel")
+		}
+	case "v": // Mark or unmark the current task for bulk actions
+		markTask(&m)
+	case "V": // Mark every task from the one marked last to the current one
+		markRange(&m)
+	case "ctrl+a": // Mark every task shown, or unmark them if they all are
+		m.MarkAll()
+		reportMarks(&m)
+	case "f": // Filter the list with a query
+		openQuery(&m)
+	case "/": // Search tasks as you type
+		m.SearchMode = true
+		m.SetStatus("Type to search descriptions, categories, tags and notes • Enter keeps the search, Esc clears it")
+	case "esc": // Clear the search, then the query
+		if m.Search != "" {
+			m.SetSearch("")
+			m.SetStatus("Search cleared")
+		} else if m.Query != nil {
+			m.SetQuery("")
+			m.SetStatus("Query cleared")
+		}
+	case "u": // Undo the last change
+		undo(&m)
+	case "ctrl+r": // Redo the last undone change
+		redo(&m)
+	}
 
-			if found {
-				m.UnarchiveTask(taskIdx)
-				storage.SaveTasks(m.Tasks)
-				m.SetStatus("Task unarchived")
-			}
+	return m, nil
+}
+
+// handleConflictMode processes keyboard input on the conflict resolution screen
+func handleConflictMode(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "m": // Keep my version
+		resolveConflict(&m, false)
+	case "t": // Take the version on disk
+		resolveConflict(&m, true)
+	case "M": // Keep my version for all remaining conflicts
+		for len(m.Conflicts) > 0 {
+			resolveConflict(&m, false)
 		}
-	case "u": // Undo last delete
-		if m.LastDeleted != nil {
-			if m.UndoDelete() {
-				storage.SaveTasks(m.Tasks)
-				m.SetStatus("Task restored")
-			}
+	case "T": // Take the version on disk for all remaining conflicts
+		for len(m.Conflicts) > 0 {
+			resolveConflict(&m, true)
 		}
 	}
 
@@ -236,37 +383,57 @@ func handleKeypress(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 func handleInputMode(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 	switch msg.String() {
 	case "enter":
+		if m.SnoozeID != "" {
+			return snoozeUntilInput(m)
+		}
+		if m.BulkPrompt != "" {
+			return bulkInput(m)
+		}
+		if m.EditingQuery {
+			return applyQueryInput(m)
+		}
 		if strings.TrimSpace(m.Input) != "" {
-			// Extract category if included in format "Category: Task description"
-			category := ""
-			description := m.Input
-
-			if parts := strings.SplitN(m.Input, ":", 2); len(parts) == 2 {
-				category = strings.TrimSpace(parts[0])
-				description = strings.TrimSpace(parts[1])
+			cleanDescription, category, priority, fields := parseTaskInput(m.Input)
+			if err := resolveDateFields(fields, time.Now()); err != nil {
+				// Keep the form open so the date can be fixed
+				m.SetStatus(fmt.Sprintf("Error: %v", err))
+				return m, nil
 			}
 
-			// Parse priority from description (e.g., "Task @priority:high")
-			cleanDescription, priority := model.ParsePriorityFromText(description)
-
-			m.AddTask(cleanDescription, category, priority)
-			storage.SaveTasks(m.Tasks)
-
-			// Show status with priority info
-			if priority != model.PriorityLow {
-				m.SetStatus(fmt.Sprintf("Task added with %s priority", priority))
+			if m.InputParentID != "" {
+				// Subtasks take their parent's category, so a colon is just
+				// part of the description
+				if category != "" {
+					cleanDescription = category + ": " + cleanDescription
+				}
+				m.AddSubtask(m.InputParentID, cleanDescription, priority, fields)
+				m.SetStatus("Subtask added")
 			} else {
-				m.SetStatus("Task added with low priority")
+				m.AddTaskWithMetadata(cleanDescription, category, priority, fields)
+
+				// Show status with priority info
+				if priority != model.PriorityLow {
+					m.SetStatus(fmt.Sprintf("Task added with %s priority", priority))
+				} else {
+					m.SetStatus("Task added with low priority")
+				}
 			}
+			saveTasks(&m)
+			CheckDependencies(&m)
 
 			// Recalculate pagination after adding task
 			m.RecalculatePagination()
 		}
 		m.InputMode = false
+		m.InputParentID = ""
 		m.Input = ""
 		m.InputCursor = 0
 	case "esc":
 		m.InputMode = false
+		m.InputParentID = ""
+		m.SnoozeID = ""
+		m.BulkPrompt = ""
+		m.EditingQuery = false
 		m.Input = ""
 		m.InputCursor = 0
 	case "left":
@@ -283,12 +450,15 @@ func handleInputMode(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 		m.DeleteTextAtCursor()
 	case "tab":
 		// Try to auto-complete with a category if typed part of one
-		if !strings.Contains(m.Input, ":") {
+		if !strings.Contains(m.Input, ":") && !m.EditingQuery {
 			partialCategory := strings.TrimSpace(m.Input)
 			if partialCategory != "" {
 				for category := range m.Categories {
 					if strings.HasPrefix(strings.ToLower(category), strings.ToLower(partialCategory)) {
 						m.Input = category + ": "
+						if m.BulkPrompt == bulkCategory {
+							m.Input = category
+						}
 						m.InputCursor = len(m.Input)
 						break
 					}
@@ -309,21 +479,17 @@ func handleInputMode(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 func handleEditMode(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 	switch msg.String() {
 	case "enter":
+		if m.SnoozeID != "" {
+			return snoozeUntilInput(m)
+		}
 		if strings.TrimSpace(m.Input) != "" {
-			// Extract category if included in format "Category: Task description"
-			category := ""
-			description := m.Input
-
-			if parts := strings.SplitN(m.Input, ":", 2); len(parts) == 2 {
-				category = strings.TrimSpace(parts[0])
-				description = strings.TrimSpace(parts[1])
+			cleanDescription, category, priority, fields := parseTaskInput(m.Input)
+			if err := resolveDateFields(fields, time.Now()); err != nil {
+				m.SetStatus(fmt.Sprintf("Error: %v", err))
+				return m, nil
 			}
 
-			// Parse priority from description (e.g., "Task @priority:high")
-			cleanDescription, priority := model.ParsePriorityFromText(description)
-
-			m.UpdateTask(m.EditingTaskIdx, cleanDescription, category, priority)
-			storage.SaveTasks(m.Tasks)
+			m.UpdateTask(m.EditingTaskID, cleanDescription, category, priority, fields)
 
 			// Show status with priority info
 			if priority != model.PriorityLow {
@@ -331,6 +497,8 @@ func handleEditMode(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 			} else {
 				m.SetStatus("Task updated with low priority")
 			}
+			saveTasks(&m)
+			CheckDependencies(&m)
 		}
 		m.EditingTask = false
 		m.Input = ""
@@ -374,3 +542,76 @@ func handleEditMode(msg tea.KeyMsg, m model.Model) (model.Model, tea.Cmd) {
 
 	return m, nil
 }
+
+// parseTaskInput splits the text typed into the add/edit form, such as
+// "Work: Write report @priority:high @due:2025-03-14", into its parts
+func parseTaskInput(input string) (string, string, model.Priority, []model.MetadataField) {
+	// Parse priority from description (e.g., "Task @priority:high")
+	text, priority := model.ParsePriorityFromText(input)
+
+	// Any other @key:value tokens become metadata. This happens before the
+	// category is split off so the colons inside tokens aren't mistaken for it.
+	text, fields := model.ParseMetadataFromText(text)
+
+	// Extract category if included in format "Category: Task description"
+	category := ""
+	description := text
+
+	if parts := strings.SplitN(text, ":", 2); len(parts) == 2 {
+		category = strings.TrimSpace(parts[0])
+		description = strings.TrimSpace(parts[1])
+	}
+
+	return description, category, priority, fields
+}
+
+// resolveDateFields replaces relative @due and @start values such as
+// tomorrow or +3d with the date they stand for, as given by model.ResolveDue
+func resolveDateFields(fields []model.MetadataField, now time.Time) error {
+	for i, field := range fields {
+		if !model.IsDateKey(field.Key) {
+			continue
+		}
+		due, err := model.ResolveDue(field.Value, now)
+		if err != nil {
+			return err
+		}
+		fields[i].Value = due
+	}
+	return nil
+}
+
+// toggleTask completes or reopens the task and reports what happened
+func toggleTask(m *model.Model, task model.Task) {
+	if m.ToggleTask(task.ID) {
+		// Show status message
+		if m.Tasks[m.TaskIndexByID(task.ID)].Done {
+			m.SetStatus("Task marked as complete")
+			if idx := m.TaskIndexByID(m.NextOccurrenceID); idx >= 0 {
+				m.SetStatus(fmt.Sprintf("Task marked as complete, next due %s", m.Tasks[idx].Metadata["due"]))
+			} else if task.Metadata["every"] != "" {
+				m.SetStatus(fmt.Sprintf("Task marked as complete; @every:%s isn't a rule tuiodo understands", task.Metadata["every"]))
+			}
+			if n := m.PendingSubtasks(task.ID); n > 0 {
+				m.CompleteSubtasksID = task.ID
+				m.SetStatus(fmt.Sprintf("Task marked as complete. Complete its %d pending subtask(s) too? (y/n)", n))
+			}
+		} else {
+			m.SetStatus("Task marked as incomplete")
+		}
+		saveTasks(m)
+	} else {
+		// If task wasn't found in main list, log an error status
+		m.SetStatus("Error: Could not find task to toggle")
+		log.Printf("Failed to toggle task: %+v", task)
+	}
+}
+
+// describeTasks names the first of the tasks, and how many others there are
+func describeTasks(tasks []model.Task) string {
+	text := fmt.Sprintf("%q", tasks[0].Description)
+	if len(tasks) > 1 {
+		text += fmt.Sprintf(" and %d more", len(tasks)-1)
+	}
+	return text
+}
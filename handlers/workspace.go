@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/spmfte/tuiodo/model"
+	"github.com/spmfte/tuiodo/storage"
+)
+
+// switchFile makes the next file in the workspace active and loads its tasks
+func switchFile(m *model.Model) {
+	next, ok := storage.NextFile()
+	if !ok && storage.ActiveFile().Name == storage.FileTree {
+		m.SetStatus("Every task file below the tree is already shown")
+		return
+	}
+	if !ok {
+		m.SetStatus(fmt.Sprintf("Only one task file: %s", storage.GetStoragePath()))
+		return
+	}
+	if m.Dirty {
+		m.SetStatus("Save your changes (ctrl+s) before switching files")
+		return
+	}
+
+	if err := storage.SwitchFile(next.Name); err != nil {
+		m.SetStatus(fmt.Sprintf("Error switching files: %v", err))
+		return
+	}
+	m.OpenFile(storage.LoadTasks())
+	m.SetStatus(fmt.Sprintf("Switched to the %s file, %s", next.Name, next.Path()))
+}
+
+// moveTask sends the current task and its subtasks to the other file in the
+// workspace. Both files are written straight away.
+func moveTask(m *model.Model) {
+	id := m.CurrentTaskID()
+	if id == "" {
+		return
+	}
+	to, ok := storage.NextFile()
+	if !ok {
+		m.SetStatus("There is no other task file to move the task to")
+		return
+	}
+	if m.Dirty {
+		m.SetStatus("Save your changes (ctrl+s) before moving tasks between files")
+		return
+	}
+
+	tasks := m.Subtree(id)
+	tasks[0].ParentID = "" // Its parent stays behind
+	if err := storage.MoveTasks(tasks, to.Name); err != nil {
+		m.SetStatus(fmt.Sprintf("Error moving task: %v", err))
+		return
+	}
+
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	m.RemoveTasks(ids)
+	m.ClearHistory() // Undoing would leave the tasks in both files
+	m.SetStatus(fmt.Sprintf("Moved %d task(s) to the %s file", len(tasks), to.Name))
+	writeTasks(m)
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"runtime"
 	"runtime/debug"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spmfte/tuiodo/config"
@@ -23,19 +25,36 @@ var (
 
 // App is the main application model
 type App struct {
-	model model.Model
-	cfg   config.Config
+	model      model.Model
+	cfg        config.Config
+	configPath string // where the active file is remembered
 }
 
-// Init initializes the application
+// fileWatchInterval is how often the task file is checked for outside changes
+const fileWatchInterval = time.Second
+
+// Init initializes the application and starts watching the task file
 func (a App) Init() tea.Cmd {
-	return nil
+	return tea.Batch(
+		handlers.WatchTaskFile(storage.Watch(fileWatchInterval)),
+		handlers.TickTimer(),
+	)
 }
 
 // Update processes messages and updates the model
 func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	a.model, cmd = handlers.Update(msg, a.model)
+
+	// Remember which file was switched to for next time
+	if active := storage.ActiveFile().Name; active != a.cfg.Files.ActiveFile {
+		a.cfg.Files.ActiveFile = active
+		if a.configPath != "" {
+			if err := config.SaveActiveFile(a.configPath, active); err != nil {
+				a.model.SetStatus(fmt.Sprintf("Error saving config: %v", err))
+			}
+		}
+	}
 	return a, cmd
 }
 
@@ -59,6 +78,17 @@ func printUsage() {
 
 Usage:
   tuiodo [options]
+  tuiodo [options] <command> [arguments]
+
+Commands:
+  backups [list]                List backups with task counts and changes since
+  backups diff <backup>         Show tasks added, removed and completed since a backup
+  backups restore <backup>      Restore a backup (the current tasks are backed up first)
+  backups pick <backup> <id>... Restore single tasks from a backup by ID
+  log [--task <id>] [--category <name>] [--since <date>] [--until <date>]
+                                Show the activity log of changes to tasks
+  list [--sort <field>] [--ids] [query]
+                                List the tasks matching a query, e.g. 'tag:api due<7d'
 
 Options:
   -h, --help                    Show this help message
@@ -67,32 +97,73 @@ Options:
   --create-default-config       Create default configuration file and exit
   --print-config               Print current configuration and exit
   -s, --storage <path>         Path to storage file (overrides config)
+  --tree <dir>                 Show the tasks of every task file below dir
   -t, --tasks-per-page <num>   Number of tasks per page (overrides config)
   --debug                      Enable debug mode with detailed logging
   --no-mouse                   Disable mouse support
   --no-color                   Disable color output
   --backup-dir <path>          Set backup directory (overrides config)
-  --max-backups <num>          Set maximum number of backups (overrides config)
+  --max-backups <num>          Keep only the newest num backups (count retention policy)
   --no-auto-save              Disable auto-save feature
   --no-backup                  Disable backup on save
   --category <name>            Start with specific category filter
-  --sort <field>              Initial sort field (priority|created|category)
-  --view <type>               Initial view (all|pending|completed)
+  --sort <field>              Initial sort field (priority|created|category|due)
+  --view <type>               Initial view (all|today|overdue|upcoming|pending|waiting|completed)
 
 Examples:
   tuiodo                                    # Start with default settings
   tuiodo --config ~/.config/tuiodo.yaml     # Use custom config file
   tuiodo --storage ~/tasks.md               # Use specific storage file
+  tuiodo --tree ~/src/monorepo              # Every TODO.md in a directory tree
   tuiodo --category Work                    # Start with Work category filter
   tuiodo --sort priority                    # Sort tasks by priority
   tuiodo --view pending                     # Show only pending tasks
+  tuiodo --view today --sort due            # What's due today, soonest first
   tuiodo --no-mouse --no-color             # Terminal-friendly mode
+  tuiodo backups                            # List backups of the task file
+  tuiodo list 'priority>=high -status:done'  # Urgent open tasks, for scripts
 
 For more information and documentation:
   https://github.com/spmfte/tuiodo
 `, Version)
 }
 
+// backupRetention converts the retention settings to a policy. The "count"
+// policy returns nil, which keeps the newest backups up to the maximum count.
+func backupRetention(cfg config.RetentionConfig) (*storage.RetentionPolicy, error) {
+	switch cfg.Policy {
+	case "count":
+		return nil, nil
+	case "tiered":
+	default:
+		return nil, fmt.Errorf("unknown backup retention policy %q (want tiered or count)", cfg.Policy)
+	}
+
+	var policy storage.RetentionPolicy
+	for _, setting := range []struct {
+		name  string
+		value string
+		into  *time.Duration
+	}{
+		{"keep_all", cfg.KeepAll, &policy.KeepAll},
+		{"hourly", cfg.Hourly, &policy.Hourly},
+		{"daily", cfg.Daily, &policy.Daily},
+		{"weekly", cfg.Weekly, &policy.Weekly},
+	} {
+		d, err := config.ParseDuration(setting.value)
+		if err != nil {
+			return nil, fmt.Errorf("retention %s: %w", setting.name, err)
+		}
+		// Only the last tier can last forever; a zero anywhere else keeps
+		// nothing in that tier
+		if strings.TrimSpace(setting.value) == "forever" && setting.name != "weekly" {
+			return nil, fmt.Errorf("retention %s: only weekly can be \"forever\"", setting.name)
+		}
+		*setting.into = d
+	}
+	return &policy, nil
+}
+
 func main() {
 	// Parse command line flags
 	flags := config.ParseFlags()
@@ -157,15 +228,54 @@ func main() {
 		}
 	}
 
+	// The global task file and the name of a project's task file
+	globalFile, err := config.ExpandPath(cfg.Files.GlobalTodoFile)
+	if err != nil {
+		globalFile = cfg.Files.GlobalTodoFile
+	}
+	storage.ConfigureFiles(globalFile, cfg.Files.DirectoryTodoFile)
+
 	// Initialize storage with full configuration
 	storage.Initialize(
 		storagePath,
 		backupDir,
-		flags.MaxBackups,
+		cfg.Storage.MaxBackups,
 		!flags.NoAutoSave,
 		!flags.NoBackup,
 	)
 
+	// Open the project and global files with the storage backend selected
+	// in the config, starting with the file that was active last time unless
+	// --storage names the file, or with --tree every task file below a
+	// directory
+	if flags.TreeRoot != "" {
+		treeRoot, err := config.ExpandPath(flags.TreeRoot)
+		if err != nil {
+			treeRoot = flags.TreeRoot
+		}
+		err = storage.OpenTree(treeRoot, cfg.Files.ExcludeDirs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if err := storage.OpenWorkspace(cfg.Storage.Backend, cfg.Files.ActiveFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.Files.ActiveFile = storage.ActiveFile().Name
+
+	policy, err := backupRetention(cfg.Storage.Retention)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	storage.ConfigureBackups(policy, cfg.Storage.CompressBackups)
+
+	// Run a command such as "tuiodo backups" instead of the interface
+	if len(flags.Args) > 0 {
+		os.Exit(runCommand(flags.Args))
+	}
+
 	// Load tasks from storage
 	tasks := storage.LoadTasks()
 
@@ -199,8 +309,16 @@ func main() {
 		switch flags.View {
 		case "all":
 			initialModel.CurrentView = model.TabAll
+		case "today":
+			initialModel.CurrentView = model.TabToday
+		case "overdue":
+			initialModel.CurrentView = model.TabOverdue
+		case "upcoming":
+			initialModel.CurrentView = model.TabUpcoming
 		case "pending":
 			initialModel.CurrentView = model.TabPending
+		case "waiting":
+			initialModel.CurrentView = model.TabWaiting
 		case "completed":
 			initialModel.CurrentView = model.TabCompleted
 		}
@@ -214,6 +332,9 @@ func main() {
 		initialModel.SortTasks(model.SortByPriority)
 	}
 
+	// Tasks blocking each other in a circle can never be started
+	handlers.CheckDependencies(&initialModel)
+
 	// Configure tea program options
 	options := []tea.ProgramOption{}
 
@@ -227,8 +348,14 @@ func main() {
 
 	// Create application instance
 	app := App{
-		model: initialModel,
-		cfg:   cfg,
+		model:      initialModel,
+		cfg:        cfg,
+		configPath: flags.ConfigFile,
+	}
+	if app.configPath == "" {
+		if path, err := config.GetConfigFilePath(); err == nil {
+			app.configPath = path
+		}
 	}
 
 	// Create and run the program
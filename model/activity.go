@@ -0,0 +1,83 @@
+package model
+
+import "time"
+
+// Actions recorded in the activity log
+const (
+	ActionCreated    = "created"
+	ActionEdited     = "edited"
+	ActionCompleted  = "completed"
+	ActionReopened   = "reopened"
+	ActionPriority   = "prioritised"
+	ActionArchived   = "archived"
+	ActionUnarchived = "unarchived"
+	ActionDeleted    = "deleted"
+)
+
+// Event is one change to a task, as recorded in the activity log
+type Event struct {
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	TaskID      string    `json:"task"`
+	Description string    `json:"description"`
+	Category    string    `json:"category,omitempty"`
+	From        string    `json:"from,omitempty"` // The old priority, for ActionPriority
+	To          string    `json:"to,omitempty"`   // The new priority, for ActionPriority
+	Actor       string    `json:"actor,omitempty"`
+	File        string    `json:"file,omitempty"`
+}
+
+// TaskEvents returns the events that turn the tasks in before into those in
+// after, matching them by ID. A task changed in several ways at once gets an
+// event for each, e.g. completed and edited.
+func TaskEvents(before, after []Task, now time.Time) []Event {
+	now = now.UTC().Truncate(time.Second)
+	event := func(action string, task Task) Event {
+		return Event{Time: now, Action: action, TaskID: task.ID, Description: task.Description, Category: task.Category}
+	}
+
+	var events []Event
+	beforeByID := indexTasks(before)
+	afterByID := indexTasks(after)
+
+	for _, task := range after {
+		old, ok := beforeByID[task.ID]
+		if !ok {
+			events = append(events, event(ActionCreated, task))
+			continue
+		}
+
+		switch {
+		case task.Done && !old.Done:
+			events = append(events, event(ActionCompleted, task))
+		case !task.Done && old.Done:
+			events = append(events, event(ActionReopened, task))
+		}
+		switch {
+		case task.Archived && !old.Archived:
+			events = append(events, event(ActionArchived, task))
+		case !task.Archived && old.Archived:
+			events = append(events, event(ActionUnarchived, task))
+		}
+		if task.Priority != old.Priority {
+			e := event(ActionPriority, task)
+			e.From, e.To = string(old.Priority), string(task.Priority)
+			events = append(events, e)
+		}
+
+		// Anything else is an edit
+		rest := old.Clone()
+		rest.Done, rest.CompletedAt = task.Done, task.CompletedAt
+		rest.Archived, rest.Priority = task.Archived, task.Priority
+		if !SameTask(rest, task) {
+			events = append(events, event(ActionEdited, task))
+		}
+	}
+
+	for _, task := range before {
+		if _, ok := afterByID[task.ID]; !ok {
+			events = append(events, event(ActionDeleted, task))
+		}
+	}
+	return events
+}
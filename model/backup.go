@@ -0,0 +1,96 @@
+package model
+
+import "time"
+
+// Backup is a snapshot of the task list kept in the backup directory
+type Backup struct {
+	Name  string    // File name within the backup directory
+	Time  time.Time // When the snapshot was taken
+	Tasks []Task
+}
+
+// TaskDiff lists how the tasks changed between a backup and the current file
+type TaskDiff struct {
+	Added     []Task // In the current file but not in the backup
+	Removed   []Task // In the backup but no longer in the current file
+	Completed []Task // Pending in the backup, done now
+	Reopened  []Task // Done in the backup, pending now
+	Changed   []Task // Edited in some other way since; the current version
+}
+
+// Empty reports whether there are no differences
+func (d TaskDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Completed) == 0 &&
+		len(d.Reopened) == 0 && len(d.Changed) == 0
+}
+
+// DiffTasks compares the tasks in a backup with the current ones, matching
+// them by ID
+func DiffTasks(backup, current []Task) TaskDiff {
+	var diff TaskDiff
+	backupByID := indexTasks(backup)
+	currentByID := indexTasks(current)
+
+	for _, task := range backup {
+		if _, ok := currentByID[task.ID]; !ok {
+			diff.Removed = append(diff.Removed, task)
+		}
+	}
+
+	for _, task := range current {
+		old, ok := backupByID[task.ID]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, task)
+		case task.Done && !old.Done:
+			diff.Completed = append(diff.Completed, task)
+		case !task.Done && old.Done:
+			diff.Reopened = append(diff.Reopened, task)
+		case !SameTask(task, old):
+			diff.Changed = append(diff.Changed, task)
+		}
+	}
+
+	return diff
+}
+
+// BackupTaskStatus describes how a task in a backup compares with the
+// current file: "removed", "completed", "reopened", "changed" or "" if it is
+// unchanged
+func BackupTaskStatus(task Task, current []Task) string {
+	for _, cur := range current {
+		if cur.ID != task.ID {
+			continue
+		}
+		switch {
+		case cur.Done && !task.Done:
+			return "completed"
+		case !cur.Done && task.Done:
+			return "reopened"
+		case !SameTask(cur, task):
+			return "changed"
+		}
+		return ""
+	}
+	return "removed"
+}
+
+// RestoreTask puts a task from a backup back into the list, replacing the
+// current version if the task still exists
+func (m *Model) RestoreTask(task Task) {
+	task = task.Clone()
+	if task.Category != "" {
+		m.Categories[task.Category] = struct{}{}
+	}
+
+	if idx := m.TaskIndexByID(task.ID); idx >= 0 {
+		m.Tasks[idx] = task
+	} else {
+		m.Tasks = append(m.Tasks, task)
+	}
+
+	if m.CurrentSort != "" {
+		m.SortTasks(m.CurrentSort)
+	}
+	m.recalculatePagination()
+}
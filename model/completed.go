@@ -0,0 +1,68 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// CompletionWindows are the "completed within" filters cycled through in the
+// list, the empty one showing every task
+var CompletionWindows = []string{"", "today", "this week", "last 7 days", "last 30 days"}
+
+// SetDone completes or reopens the task, recording when it was completed
+func (t *Task) SetDone(done bool) {
+	if done == t.Done {
+		return
+	}
+
+	t.Done = done
+	if done {
+		t.CompletedAt = time.Now().UTC().Truncate(time.Second)
+	} else {
+		t.CompletedAt = time.Time{}
+	}
+}
+
+// CompletedSince returns the start of a completion window relative to now:
+// midnight for "today", Monday for "this week", and N days back for
+// "last N days". ok is false for a window it doesn't recognize.
+func CompletedSince(window string, now time.Time) (since time.Time, ok bool) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch window {
+	case "today":
+		return midnight, true
+	case "this week":
+		daysSinceMonday := (int(now.Weekday()) + 6) % 7
+		return midnight.AddDate(0, 0, -daysSinceMonday), true
+	}
+
+	var days int
+	if _, err := fmt.Sscanf(window, "last %d days", &days); err == nil && days > 0 {
+		return now.AddDate(0, 0, -days), true
+	}
+	return time.Time{}, false
+}
+
+// CompletedWithin reports whether the task was completed within the window.
+// Tasks completed without a recorded time never are.
+func (t Task) CompletedWithin(window string, now time.Time) bool {
+	since, ok := CompletedSince(window, now)
+	return ok && t.Done && !t.CompletedAt.IsZero() && !t.CompletedAt.Before(since)
+}
+
+// CycleCompletedWindow switches to the next "completed within" filter
+func (m *Model) CycleCompletedWindow() {
+	next := 0
+	for i, window := range CompletionWindows {
+		if window == m.CompletedWindow {
+			next = (i + 1) % len(CompletionWindows)
+			break
+		}
+	}
+	m.CompletedWindow = CompletionWindows[next]
+
+	m.Cursor = 0
+	m.Pagination.Page = 0
+	m.recalculatePagination()
+}
@@ -0,0 +1,65 @@
+package model
+
+import "time"
+
+// startKey returns the metadata key holding the task's start date. @wait is
+// another name for @start; a task that uses it keeps it.
+func (t Task) startKey() string {
+	if t.Metadata["start"] == "" && t.Metadata["wait"] != "" {
+		return "wait"
+	}
+	return "start"
+}
+
+// Start returns the task's @start (or @wait) date in local time and the
+// layout it was written in. ok is false if it has none or it can't be read.
+func (t Task) Start() (start time.Time, layout string, ok bool) {
+	return parseDate(t.Metadata[t.startKey()])
+}
+
+// Waiting reports whether the task is deferred until a start date that
+// hasn't arrived yet. A date without a time arrives at the start of that
+// day. Done and archived tasks aren't waiting.
+func (t Task) Waiting(now time.Time) bool {
+	start, _, ok := t.Start()
+	return ok && !t.Done && !t.Archived && start.After(now)
+}
+
+// SnoozeDate returns the date a task snoozed for the given number of days
+// starts on: that many days after its start date if it is still waiting,
+// otherwise after today. The time of day of its start date is kept.
+func (t Task) SnoozeDate(days int, now time.Time) string {
+	if start, layout, ok := t.Start(); ok && t.Waiting(now) {
+		return start.AddDate(0, 0, days).Format(layout)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	return today.AddDate(0, 0, days).Format(DateLayout)
+}
+
+// Snooze defers the task with the given ID until start, a date in DateLayout
+// or DateTimeLayout. It returns false if there is no such task.
+func (m *Model) Snooze(id, start string) bool {
+	idx := m.TaskIndexByID(id)
+	if idx < 0 {
+		return false
+	}
+	m.Tasks[idx].SetMetadata(m.Tasks[idx].startKey(), start)
+	m.recalculatePagination()
+	return true
+}
+
+// waitingIDs returns the IDs of the tasks waiting for their start date,
+// along with their subtasks, which wait with them
+func (m Model) waitingIDs(now time.Time) map[string]bool {
+	waiting := make(map[string]bool)
+	for _, task := range m.Tasks {
+		if !task.Waiting(now) || waiting[task.ID] {
+			continue
+		}
+		waiting[task.ID] = true
+		for _, id := range m.descendantIDs(task.ID) {
+			waiting[id] = true
+		}
+	}
+	return waiting
+}
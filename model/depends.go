@@ -0,0 +1,135 @@
+package model
+
+import "strings"
+
+// dependencyKeys are the metadata keys naming the tasks a task waits for.
+// @after is another name for @blocked-by.
+var dependencyKeys = []string{"blocked-by", "after"}
+
+// Blockers returns the IDs of the tasks this one waits for, from its
+// @blocked-by and @after metadata. Each may list several IDs separated by
+// commas.
+func (t Task) Blockers() []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, key := range dependencyKeys {
+		for _, id := range strings.Split(t.Metadata[key], ",") {
+			id = strings.TrimSpace(id)
+			if id != "" && id != t.ID && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// OpenBlockers returns the tasks the task with the given ID waits for that
+// aren't done yet. IDs of tasks that aren't in the list are ignored.
+func (m Model) OpenBlockers(id string) []Task {
+	idx := m.TaskIndexByID(id)
+	if idx < 0 {
+		return nil
+	}
+	var open []Task
+	for _, blocker := range m.Tasks[idx].Blockers() {
+		if i := m.TaskIndexByID(blocker); i >= 0 && !m.Tasks[i].Done {
+			open = append(open, m.Tasks[i])
+		}
+	}
+	return open
+}
+
+// IsBlocked reports whether the task with the given ID is pending and
+// waits for a task that isn't done yet
+func (m Model) IsBlocked(id string) bool {
+	idx := m.TaskIndexByID(id)
+	return idx >= 0 && !m.Tasks[idx].Done && len(m.OpenBlockers(id)) > 0
+}
+
+// Dependants returns the tasks that wait for the task with the given ID
+func (m Model) Dependants(id string) []Task {
+	var dependants []Task
+	for _, task := range m.Tasks {
+		for _, blocker := range task.Blockers() {
+			if blocker == id {
+				dependants = append(dependants, task)
+				break
+			}
+		}
+	}
+	return dependants
+}
+
+// blockedIDs returns the IDs of every blocked task, as by IsBlocked
+func (m Model) blockedIDs() map[string]bool {
+	done := make(map[string]bool, len(m.Tasks))
+	for _, task := range m.Tasks {
+		done[task.ID] = task.Done
+	}
+
+	blocked := make(map[string]bool)
+	for _, task := range m.Tasks {
+		if task.Done {
+			continue
+		}
+		for _, id := range task.Blockers() {
+			if isDone, ok := done[id]; ok && !isDone {
+				blocked[task.ID] = true
+				break
+			}
+		}
+	}
+	return blocked
+}
+
+// DependencyCycles returns the cycles among the tasks' @blocked-by and
+// @after relations, which leave every task on them blocked for good. Each
+// is the IDs along the cycle, starting and ending with the same one.
+func (m Model) DependencyCycles() [][]string {
+	blockers := make(map[string][]string, len(m.Tasks))
+	for _, task := range m.Tasks {
+		blockers[task.ID] = task.Blockers()
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(m.Tasks))
+	var path []string
+	var cycles [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		path = append(path, id)
+		for _, next := range blockers[id] {
+			if _, ok := blockers[next]; !ok {
+				continue // Not a task in the list
+			}
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case visiting:
+				// Back to a task further up the path
+				start := len(path) - 1
+				for path[start] != next {
+					start--
+				}
+				cycle := append([]string(nil), path[start:]...)
+				cycles = append(cycles, append(cycle, next))
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+	}
+
+	for _, task := range m.Tasks {
+		if state[task.ID] == unvisited {
+			visit(task.ID)
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,195 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts of @due dates, without and with a time of day
+const (
+	DateLayout     = "2006-01-02"
+	DateTimeLayout = "2006-01-02T15:04"
+)
+
+// UpcomingDays is how far ahead the Upcoming tab looks
+const UpcomingDays = 7
+
+// DueStatus says when a task is due relative to now
+type DueStatus int
+
+const (
+	DueNone     DueStatus = iota // No due date, or the task is done
+	DueOverdue                   // The due date (and time, if given) has passed
+	DueToday                     // Due later today
+	DueUpcoming                  // Due within the next UpcomingDays days
+	DueLater                     // Due after that
+)
+
+// Due returns the task's @due date in local time and the layout it was
+// written in. ok is false if it has none or it can't be read.
+func (t Task) Due() (due time.Time, layout string, ok bool) {
+	return parseDate(t.Metadata["due"])
+}
+
+// parseDate reads a date in DateLayout or DateTimeLayout in local time
+func parseDate(value string) (date time.Time, layout string, ok bool) {
+	if value == "" {
+		return time.Time{}, "", false
+	}
+	for _, layout := range []string{DateLayout, DateTimeLayout} {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, layout, true
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// dueTimePattern splits the time of day off a relative due date
+var dueTimePattern = regexp.MustCompile(`^(.+)t(\d{1,2}:\d{2})$`)
+
+// ResolveDue turns a @due (or @start) value typed into the add/edit form into an
+// absolute date in DateLayout, or DateTimeLayout if it has a time of day.
+// Besides such dates it accepts today, tomorrow, a weekday such as fri (the
+// next one after today), a count of days, weeks, months or years from today
+// such as +3d or +2w, next-week (the coming Monday) and next-month (the first
+// of next month). Any of these can be followed by a time, as in tomorrowT09:30.
+func ResolveDue(value string, now time.Time) (string, error) {
+	text := strings.ToLower(strings.TrimSpace(value))
+	day, clock := text, ""
+	if match := dueTimePattern.FindStringSubmatch(text); match != nil {
+		day, clock = match[1], match[2]
+	}
+
+	date, ok := resolveDay(day, now)
+	if !ok {
+		return "", fmt.Errorf("can't read due date %q", value)
+	}
+	if clock == "" {
+		return date.Format(DateLayout), nil
+	}
+	at, err := time.Parse("15:04", clock)
+	if err != nil {
+		return "", fmt.Errorf("can't read the time in due date %q", value)
+	}
+	due := time.Date(date.Year(), date.Month(), date.Day(), at.Hour(), at.Minute(), 0, 0, time.Local)
+	return due.Format(DateTimeLayout), nil
+}
+
+// IsDateKey reports whether the metadata key holds a date, which the
+// add/edit form resolves with ResolveDue
+func IsDateKey(key string) bool {
+	return key == "due" || key == "start" || key == "wait"
+}
+
+// resolveDay returns the day named by a date or one of the relative forms
+// accepted by ResolveDue, at midnight
+func resolveDay(day string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	if date, err := time.ParseInLocation(DateLayout, day, time.Local); err == nil {
+		return date, true
+	}
+
+	switch day {
+	case "today":
+		return today, true
+	case "tomorrow", "tmr":
+		return today.AddDate(0, 0, 1), true
+	case "next-week":
+		return nextWeekday(today, time.Monday), true
+	case "next-month":
+		return time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, time.Local), true
+	}
+	if weekday, ok := weekdayNames[day]; ok {
+		return nextWeekday(today, weekday), true
+	}
+
+	if len(day) > 2 && day[0] == '+' && day[1] != '-' {
+		return addPeriod(today, day[1:])
+	}
+	return time.Time{}, false
+}
+
+// addPeriod adds a count of days, weeks, months or years such as 3d or 2w
+// to a day. Negative counts such as -3d go back.
+func addPeriod(day time.Time, period string) (time.Time, bool) {
+	if len(period) < 2 {
+		return time.Time{}, false
+	}
+	n, err := strconv.Atoi(period[:len(period)-1])
+	if err != nil || period[0] == '+' {
+		return time.Time{}, false
+	}
+	switch period[len(period)-1] {
+	case 'd':
+		return day.AddDate(0, 0, n), true
+	case 'w':
+		return day.AddDate(0, 0, 7*n), true
+	case 'm':
+		return addMonths(day, n), true
+	case 'y':
+		return addMonths(day, 12*n), true
+	}
+	return time.Time{}, false
+}
+
+// nextWeekday returns the first day after today that falls on weekday
+func nextWeekday(today time.Time, weekday time.Weekday) time.Time {
+	days := (int(weekday)-int(today.Weekday())+6)%7 + 1
+	return today.AddDate(0, 0, days)
+}
+
+// DueStatus returns when the task is due relative to now. A date without a
+// time is due by the end of that day. Done and archived tasks aren't due.
+func (t Task) DueStatus(now time.Time) DueStatus {
+	due, layout, ok := t.Due()
+	if !ok || t.Done || t.Archived {
+		return DueNone
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	tomorrow := today.AddDate(0, 0, 1)
+	deadline := due
+	if layout == DateLayout {
+		deadline = due.AddDate(0, 0, 1)
+	}
+
+	switch {
+	case !deadline.After(now):
+		return DueOverdue
+	case due.Before(tomorrow):
+		return DueToday
+	case due.Before(tomorrow.AddDate(0, 0, UpcomingDays)):
+		return DueUpcoming
+	}
+	return DueLater
+}
+
+// dueBefore orders tasks by due date, earliest first, with tasks that have
+// no due date last
+func dueBefore(a, b Task) bool {
+	dueA, _, okA := a.Due()
+	dueB, _, okB := b.Due()
+	if okA != okB {
+		return okA
+	}
+	return okA && dueA.Before(dueB)
+}
+
+// inDueView reports whether the task belongs in the Today, Overdue or
+// Upcoming tab. Today includes overdue tasks so nothing late is missed.
+func (t Task) inDueView(view TabView, now time.Time) bool {
+	status := t.DueStatus(now)
+	switch view {
+	case TabToday:
+		return status == DueToday || status == DueOverdue
+	case TabOverdue:
+		return status == DueOverdue
+	case TabUpcoming:
+		return status == DueUpcoming
+	}
+	return false
+}
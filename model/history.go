@@ -0,0 +1,152 @@
+package model
+
+import "strings"
+
+// UndoLimit is how many changes can be undone
+const UndoLimit = 100
+
+// Snapshot is the state an undo or redo returns the model to
+type Snapshot struct {
+	tasks  []Task
+	cursor int
+	page   int
+	sort   SortType
+}
+
+// change is an entry in the undo or redo stack: the states before and after
+// a change to the tasks, and the status it reported
+type change struct {
+	label         string
+	before, after Snapshot
+}
+
+// History holds the changes to the tasks that can be undone and redone.
+// Handlers take a Checkpoint before acting on a key and Record it afterwards,
+// so every change made through them can be undone.
+type History struct {
+	undo, redo []change
+	applied    bool // An undo, redo or ClearHistory happened since the last Checkpoint
+}
+
+// Checkpoint captures the state of the tasks, to Record after they are changed
+func (m *Model) Checkpoint() Snapshot {
+	m.History.applied = false
+	return m.snapshot()
+}
+
+// Record adds the change made since before was taken to the undo stack,
+// labelled with the current status message. Nothing is recorded if the
+// tasks didn't change, if the change was itself an undo or redo, or if the
+// history was cleared while making it. Changes that were undone can no
+// longer be redone.
+func (m *Model) Record(before Snapshot) {
+	if m.History.applied || sameTaskList(before.tasks, m.Tasks) {
+		return
+	}
+	// The first sentence of the status, without any question that follows
+	label, _, _ := strings.Cut(m.StatusMessage, ". ")
+	m.History.undo = append(m.History.undo, change{label: label, before: before, after: m.snapshot()})
+	if len(m.History.undo) > UndoLimit {
+		m.History.undo = m.History.undo[len(m.History.undo)-UndoLimit:]
+	}
+	m.History.redo = nil
+}
+
+// CanUndo reports whether there is a change to undo
+func (m Model) CanUndo() bool {
+	return len(m.History.undo) > 0
+}
+
+// CanRedo reports whether there is an undone change to redo
+func (m Model) CanRedo() bool {
+	return len(m.History.redo) > 0
+}
+
+// Undo returns the tasks, cursor and sort to how they were before the last
+// recorded change and returns that change's label. ok is false if there is
+// nothing to undo, or if the tasks were changed since by something that
+// can't be undone, such as a reload, in which case the history is cleared.
+func (m *Model) Undo() (label string, ok bool) {
+	if !m.CanUndo() {
+		return "", false
+	}
+	last := m.History.undo[len(m.History.undo)-1]
+	if !sameTaskList(last.after.tasks, m.Tasks) {
+		m.ClearHistory()
+		return "", false
+	}
+
+	m.History.undo = m.History.undo[:len(m.History.undo)-1]
+	m.History.redo = append(m.History.redo, last)
+	m.restore(last.before)
+	return last.label, true
+}
+
+// Redo applies the last undone change again and returns its label. ok is
+// false as for Undo.
+func (m *Model) Redo() (label string, ok bool) {
+	if !m.CanRedo() {
+		return "", false
+	}
+	next := m.History.redo[len(m.History.redo)-1]
+	if !sameTaskList(next.before.tasks, m.Tasks) {
+		m.ClearHistory()
+		return "", false
+	}
+
+	m.History.redo = m.History.redo[:len(m.History.redo)-1]
+	m.History.undo = append(m.History.undo, next)
+	m.restore(next.after)
+	return next.label, true
+}
+
+// ClearHistory forgets every change, e.g. once the tasks were replaced in a
+// way that can't be undone. The change being made isn't recorded either.
+func (m *Model) ClearHistory() {
+	m.History.undo = nil
+	m.History.redo = nil
+	m.History.applied = true
+}
+
+// snapshot copies the current state
+func (m Model) snapshot() Snapshot {
+	return Snapshot{tasks: cloneTasks(m.Tasks), cursor: m.Cursor, page: m.Pagination.Page, sort: m.CurrentSort}
+}
+
+// restore returns the model to a snapshot
+func (m *Model) restore(s Snapshot) {
+	m.Tasks = cloneTasks(s.tasks)
+	for _, task := range m.Tasks {
+		if task.Category != "" {
+			m.Categories[task.Category] = struct{}{}
+		}
+	}
+	m.CurrentSort = s.sort
+	m.Cursor = s.cursor
+	m.Pagination.Page = s.page
+	m.recalculatePagination()
+	m.History.applied = true
+}
+
+// cloneTasks copies a task list, metadata included
+func cloneTasks(tasks []Task) []Task {
+	clone := make([]Task, len(tasks))
+	for i, task := range tasks {
+		clone[i] = task.Clone()
+	}
+	return clone
+}
+
+// sameTaskList reports whether two lists hold the same tasks in the same
+// order
+func sameTaskList(a, b []Task) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !SameTask(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// taskIDLength is the number of base36 characters in a task ID
+const taskIDLength = 6
+
+// taskIDSpace is the number of distinct task IDs (36^taskIDLength)
+var taskIDSpace = new(big.Int).Exp(big.NewInt(36), big.NewInt(taskIDLength), nil)
+
+// NewTaskID returns a random short identifier for a new task
+func NewTaskID() string {
+	n, err := rand.Int(rand.Reader, taskIDSpace)
+	if err != nil {
+		// crypto/rand never fails on supported platforms, but fall back to a
+		// derived ID rather than panicking
+		return DeriveTaskID(strconv.FormatInt(time.Now().UnixNano(), 10))
+	}
+	return formatTaskID(n)
+}
+
+// DeriveTaskID returns a short identifier computed from seed. It is used for
+// tasks that don't have an @id yet, so they keep the same ID across loads as
+// long as their line doesn't change.
+func DeriveTaskID(seed string) string {
+	sum := sha1.Sum([]byte(seed))
+	n := new(big.Int).SetBytes(sum[:])
+	return formatTaskID(n.Mod(n, taskIDSpace))
+}
+
+// formatTaskID renders n as a zero-padded base36 ID
+func formatTaskID(n *big.Int) string {
+	id := n.Text(36)
+	for len(id) < taskIDLength {
+		id = "0" + id
+	}
+	return id
+}
+
+// uniqueTaskID returns a new random ID not used by any task in the model
+func (m *Model) uniqueTaskID() string {
+	for {
+		id := NewTaskID()
+		if m.TaskIndexByID(id) < 0 {
+			return id
+		}
+	}
+}
+
+// TaskIndexByID returns the index in Tasks of the task with the given ID, or -1
+func (m Model) TaskIndexByID(id string) int {
+	if id == "" {
+		return -1
+	}
+	for i, task := range m.Tasks {
+		if task.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// CurrentTask returns the task under the cursor in the current view
+func (m Model) CurrentTask() (Task, bool) {
+	visibleTasks := m.GetVisibleTasks()
+	if m.Cursor < 0 || m.Cursor >= len(visibleTasks) {
+		return Task{}, false
+	}
+	return visibleTasks[m.Cursor], true
+}
+
+// CurrentTaskID returns the ID of the task under the cursor, or "" if there is none
+func (m Model) CurrentTaskID() string {
+	task, ok := m.CurrentTask()
+	if !ok {
+		return ""
+	}
+	return task.ID
+}
+
+// SelectTask moves the cursor, and the page if needed, to the task with the
+// given ID. It returns false if the task isn't in the current view.
+func (m *Model) SelectTask(id string) bool {
+	for i, task := range m.GetFilteredTasks() {
+		if task.ID != id {
+			continue
+		}
+		if m.Pagination.ItemsPerPage > 0 {
+			m.Pagination.Page = i / m.Pagination.ItemsPerPage
+			m.Cursor = i % m.Pagination.ItemsPerPage
+		} else {
+			m.Cursor = i
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+// deletedValue is shown for the side of a conflict that deleted the task
+const deletedValue = "(deleted)"
+
+// Conflict is a change to a task that was made both in tuiodo (ours) and
+// outside it (theirs) and can't be merged automatically
+type Conflict struct {
+	TaskID      string
+	Description string // Description of the task, for display
+	Field       string // Conflicting field, metadata as "@key"; empty if one side deleted the task
+	Base        string // Value both sides started from
+	Ours        string // Value in tuiodo
+	Theirs      string // Value on disk
+
+	theirs Task // Their version of the task, to copy the field from
+}
+
+// taskField reads and copies one field of a task for merging
+type taskField struct {
+	name string
+	get  func(Task) string
+	copy func(dst *Task, src Task)
+}
+
+// taskFields are the task fields merged individually. Metadata keys are
+// handled separately since they differ per task.
+var taskFields = []taskField{
+	{"description", func(t Task) string { return t.Description }, func(d *Task, s Task) { d.Description = s.Description }},
+	{"notes", func(t Task) string { return t.Notes }, func(d *Task, s Task) { d.Notes = s.Notes }},
+	{"done", func(t Task) string { return strconv.FormatBool(t.Done) }, func(d *Task, s Task) { d.Done = s.Done }},
+	{"category", func(t Task) string { return t.Category }, func(d *Task, s Task) { d.Category = s.Category }},
+	{"parent", func(t Task) string { return t.ParentID }, func(d *Task, s Task) { d.ParentID = s.ParentID }},
+	{"priority", func(t Task) string { return string(t.Priority) }, func(d *Task, s Task) { d.Priority = s.Priority }},
+	{"archived", func(t Task) string { return strconv.FormatBool(t.Archived) }, func(d *Task, s Task) { d.Archived = s.Archived }},
+	{"created", func(t Task) string { return t.CreatedAt.Format(time.RFC3339) }, func(d *Task, s Task) { d.CreatedAt = s.CreatedAt }},
+	{"completed", func(t Task) string { return t.CompletedAt.Format(time.RFC3339) }, func(d *Task, s Task) { d.CompletedAt = s.CompletedAt }},
+}
+
+// MergeTasks does a three-way merge of changes made to the task file outside
+// tuiodo (theirs) with unsaved changes made in tuiodo (ours). Both started
+// from base, the tasks as they were last loaded. Tasks are matched by ID:
+//   - a task changed on only one side takes that side's version
+//   - a task deleted on one side and unchanged on the other is deleted
+//   - tasks added on either side are kept
+//   - a task changed on both sides is merged field by field
+//
+// A field changed to different values on both sides, or a task deleted on one
+// side and changed on the other, is returned as a conflict. The merged tasks
+// hold our value for conflicting fields and keep tasks deleted on one side,
+// until the conflict is resolved with ResolveConflict.
+//
+// The result follows our order, with tasks that only exist on their side
+// appended in their order.
+func MergeTasks(base, theirs, ours []Task) ([]Task, []Conflict) {
+	baseByID := indexTasks(base)
+	theirsByID := indexTasks(theirs)
+	oursByID := indexTasks(ours)
+
+	merged := make([]Task, 0, len(ours))
+	var conflicts []Conflict
+
+	for _, task := range ours {
+		baseTask, inBase := baseByID[task.ID]
+		theirTask, inTheirs := theirsByID[task.ID]
+
+		switch {
+		case !inBase:
+			// Added by us
+			merged = append(merged, task)
+		case !inTheirs:
+			// Deleted outside, which wins unless we changed the task since
+			if !SameTask(task, baseTask) {
+				merged = append(merged, task)
+				conflicts = append(conflicts, Conflict{
+					TaskID:      task.ID,
+					Description: task.Description,
+					Base:        baseTask.Description,
+					Ours:        task.Description,
+					Theirs:      deletedValue,
+				})
+			}
+		case SameTask(task, baseTask):
+			merged = append(merged, theirTask)
+		case SameTask(theirTask, baseTask):
+			merged = append(merged, task)
+		default:
+			result, fieldConflicts := mergeTask(baseTask, theirTask, task)
+			merged = append(merged, result)
+			conflicts = append(conflicts, fieldConflicts...)
+		}
+	}
+
+	for _, task := range theirs {
+		if _, ok := oursByID[task.ID]; ok {
+			continue
+		}
+
+		baseTask, inBase := baseByID[task.ID]
+		switch {
+		case !inBase:
+			// Added outside
+			merged = append(merged, task)
+		case !SameTask(task, baseTask):
+			// Deleted by us but changed outside since
+			merged = append(merged, task)
+			conflicts = append(conflicts, Conflict{
+				TaskID:      task.ID,
+				Description: task.Description,
+				Base:        baseTask.Description,
+				Ours:        deletedValue,
+				Theirs:      task.Description,
+				theirs:      task,
+			})
+		}
+	}
+
+	return merged, conflicts
+}
+
+// mergeTask merges a task changed on both sides field by field
+func mergeTask(base, theirs, ours Task) (Task, []Conflict) {
+	merged := ours.Clone()
+	var conflicts []Conflict
+
+	for _, field := range taskFields {
+		b, t, o := field.get(base), field.get(theirs), field.get(ours)
+		switch {
+		case t == o || t == b:
+			// Same on both sides, or only we changed it
+		case o == b:
+			field.copy(&merged, theirs)
+		default:
+			conflicts = append(conflicts, Conflict{
+				TaskID:      ours.ID,
+				Description: ours.Description,
+				Field:       field.name,
+				Base:        b,
+				Ours:        o,
+				Theirs:      t,
+				theirs:      theirs,
+			})
+		}
+	}
+
+	for _, key := range metadataKeyUnion(base, theirs, ours) {
+		b, t, o := base.Metadata[key], theirs.Metadata[key], ours.Metadata[key]
+		switch {
+		case t == o || t == b:
+		case o == b:
+			copyMetadata(&merged, theirs, key)
+		default:
+			conflicts = append(conflicts, Conflict{
+				TaskID:      ours.ID,
+				Description: ours.Description,
+				Field:       "@" + key,
+				Base:        b,
+				Ours:        o,
+				Theirs:      t,
+				theirs:      theirs,
+			})
+		}
+	}
+
+	return merged, conflicts
+}
+
+// ResolveConflict settles a conflict returned by MergeTasks on the merged
+// tasks, keeping our side or taking theirs
+func (m *Model) ResolveConflict(c Conflict, takeTheirs bool) {
+	idx := m.TaskIndexByID(c.TaskID)
+	if idx < 0 {
+		return
+	}
+
+	chosen := c.Ours
+	if takeTheirs {
+		chosen = c.Theirs
+	}
+
+	switch {
+	case c.Field == "":
+		// One side deleted the task; the merged tasks still have it
+		if chosen == deletedValue {
+			m.Tasks = append(m.Tasks[:idx], m.Tasks[idx+1:]...)
+		}
+	case !takeTheirs:
+		// The merged tasks already hold our value
+	case c.Field[0] == '@':
+		copyMetadata(&m.Tasks[idx], c.theirs, c.Field[1:])
+	default:
+		for _, field := range taskFields {
+			if field.name == c.Field {
+				field.copy(&m.Tasks[idx], c.theirs)
+			}
+		}
+	}
+
+	m.recalculatePagination()
+}
+
+// SameTask reports whether two tasks have the same content. The order their
+// metadata was written in doesn't count.
+func SameTask(a, b Task) bool {
+	if a.ID != b.ID ||
+		a.ParentID != b.ParentID ||
+		a.Description != b.Description ||
+		a.Notes != b.Notes ||
+		a.Done != b.Done ||
+		a.Category != b.Category ||
+		a.Priority != b.Priority ||
+		!a.CreatedAt.Equal(b.CreatedAt) ||
+		!a.CompletedAt.Equal(b.CompletedAt) ||
+		a.Archived != b.Archived ||
+		len(a.Metadata) != len(b.Metadata) {
+		return false
+	}
+
+	for key, value := range a.Metadata {
+		if other, ok := b.Metadata[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// copyMetadata copies one metadata value from src to dst, removing it from dst
+// if src doesn't have it
+func copyMetadata(dst *Task, src Task, key string) {
+	if value, ok := src.Metadata[key]; ok {
+		dst.SetMetadata(key, value)
+	} else {
+		dst.DeleteMetadata(key)
+	}
+}
+
+// metadataKeyUnion returns every metadata key of the given tasks, in order
+func metadataKeyUnion(tasks ...Task) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, task := range tasks {
+		for _, key := range task.MetadataKeys() {
+			if !seen[key] {
+				keys = append(keys, key)
+				seen[key] = true
+			}
+		}
+	}
+	return keys
+}
+
+// indexTasks maps task IDs to tasks
+func indexTasks(tasks []Task) map[string]Task {
+	byID := make(map[string]Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	return byID
+}
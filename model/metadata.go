@@ -0,0 +1,187 @@
+package model
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// metadataPattern matches a single @key:value token. The token has to start
+// the text or follow whitespace so e-mail addresses aren't mistaken for one.
+var metadataPattern = regexp.MustCompile(`(^|\s)@([A-Za-z][A-Za-z0-9_-]*):(\S+)`)
+
+// MetadataField is a single @key:value token
+type MetadataField struct {
+	Key   string
+	Value string
+}
+
+// ParseMetadataFromText removes every @key:value token from text and returns
+// the cleaned text along with the tokens in the order they appeared
+func ParseMetadataFromText(text string) (string, []MetadataField) {
+	var fields []MetadataField
+	for _, match := range metadataPattern.FindAllStringSubmatch(text, -1) {
+		fields = append(fields, MetadataField{Key: match[2], Value: match[3]})
+	}
+	return strings.TrimSpace(metadataPattern.ReplaceAllString(text, "")), fields
+}
+
+// ApplyMetadata stores parsed tokens on the task. Tokens tuiodo understands
+// (id, priority, archived, created, completed, tag) update the matching
+// fields; everything else is kept in Metadata so it can be written back
+// unchanged. A key that repeats within fields has its values joined with
+// commas.
+func (t *Task) ApplyMetadata(fields []MetadataField) {
+	seen := make(map[string]bool, len(fields))
+
+	for _, field := range fields {
+		switch field.Key {
+		case "priority":
+			if priority, ok := parsePriority(field.Value); ok {
+				t.Priority = priority
+				t.recordMetadataKey("priority")
+				continue
+			}
+		case "archived":
+			if field.Value == "true" || field.Value == "false" {
+				t.Archived = field.Value == "true"
+				t.recordMetadataKey("archived")
+				continue
+			}
+		case "created":
+			if created, err := time.Parse(time.RFC3339, field.Value); err == nil {
+				t.CreatedAt = created
+				t.recordMetadataKey("created")
+				continue
+			}
+		case "completed":
+			if completed, err := time.Parse(time.RFC3339, field.Value); err == nil {
+				t.CompletedAt = completed
+				t.recordMetadataKey("completed")
+				continue
+			}
+		case "tag":
+			t.AddTag(field.Value)
+			continue
+		case "id":
+			t.ID = field.Value
+			t.recordMetadataKey("id")
+			continue
+		}
+
+		value := field.Value
+		if seen[field.Key] {
+			value = t.Metadata[field.Key] + "," + value
+		}
+		seen[field.Key] = true
+		t.SetMetadata(field.Key, value)
+	}
+}
+
+// SetMetadata sets a metadata value, remembering where the key goes when the
+// task is written out
+func (t *Task) SetMetadata(key, value string) {
+	if t.Metadata == nil {
+		t.Metadata = make(map[string]string)
+	}
+	t.Metadata[key] = value
+	t.recordMetadataKey(key)
+}
+
+// DeleteMetadata removes a metadata value
+func (t *Task) DeleteMetadata(key string) {
+	delete(t.Metadata, key)
+}
+
+// Tags returns the task's tags
+func (t Task) Tags() []string {
+	if t.Metadata["tags"] == "" {
+		return nil
+	}
+	return strings.Split(t.Metadata["tags"], ",")
+}
+
+// AddTag adds a tag if the task doesn't have it yet
+func (t *Task) AddTag(tag string) {
+	tags := t.Tags()
+	for _, existing := range tags {
+		if existing == tag {
+			return
+		}
+	}
+	t.SetMetadata("tags", strings.Join(append(tags, tag), ","))
+}
+
+// RemoveTag removes a tag if the task has it
+func (t *Task) RemoveTag(tag string) {
+	var kept []string
+	for _, existing := range t.Tags() {
+		if existing != tag {
+			kept = append(kept, existing)
+		}
+	}
+	if len(kept) == 0 {
+		t.DeleteMetadata("tags")
+		return
+	}
+	t.SetMetadata("tags", strings.Join(kept, ","))
+}
+
+// MetadataKeys returns the keys of Metadata in the order they appeared in the
+// source, followed by any newer keys in alphabetical order
+func (t Task) MetadataKeys() []string {
+	keys := make([]string, 0, len(t.Metadata))
+	seen := make(map[string]bool, len(t.Metadata))
+
+	for _, key := range t.MetadataOrder {
+		if _, ok := t.Metadata[key]; ok && !seen[key] {
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+
+	var rest []string
+	for key := range t.Metadata {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(keys, rest...)
+}
+
+// Clone returns a copy of the task that shares no maps or slices with it
+func (t Task) Clone() Task {
+	if t.Metadata != nil {
+		metadata := make(map[string]string, len(t.Metadata))
+		for key, value := range t.Metadata {
+			metadata[key] = value
+		}
+		t.Metadata = metadata
+	}
+	if t.MetadataOrder != nil {
+		t.MetadataOrder = append([]string(nil), t.MetadataOrder...)
+	}
+	return t
+}
+
+// recordMetadataKey remembers the position of a key the first time it is seen
+func (t *Task) recordMetadataKey(key string) {
+	for _, existing := range t.MetadataOrder {
+		if existing == key {
+			return
+		}
+	}
+	t.MetadataOrder = append(t.MetadataOrder, key)
+}
+
+// parsePriority converts a priority name to a Priority
+func parsePriority(value string) (Priority, bool) {
+	switch Priority(value) {
+	case PriorityCritical, PriorityHigh, PriorityMedium, PriorityLow:
+		return Priority(value), true
+	}
+	return PriorityNone, false
+}
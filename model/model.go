@@ -20,13 +20,22 @@ const (
 
 // Task represents a single TODO item
 type Task struct {
+	ID          string // Short persistent identifier, stored as @id
+	ParentID    string // ID of the task this is a subtask of, if any
 	Description string
+	Notes       string // Free-form text below the task, possibly several lines
 	Done        bool
 	Category    string
 	Priority    Priority
 	CreatedAt   time.Time         // When the task was created
+	CompletedAt time.Time         // When the task was last completed; zero while pending
 	Archived    bool              // Whether the task is archived
 	Metadata    map[string]string // Additional metadata like due dates, tags, status
+	Source      string            // Directory of the file the task came from, when several are shown
+
+	// MetadataOrder records the order metadata keys appeared in the source so
+	// they can be written back the same way
+	MetadataOrder []string
 }
 
 // TabView represents the current view/filter mode
@@ -34,8 +43,11 @@ type TabView string
 
 const (
 	TabAll       TabView = "all"
-	TabToday     TabView = "today"
+	TabToday     TabView = "today"    // Pending tasks due today, including overdue ones
+	TabOverdue   TabView = "overdue"  // Pending tasks past their due date
+	TabUpcoming  TabView = "upcoming" // Pending tasks due in the next UpcomingDays days
 	TabPending   TabView = "pending"
+	TabWaiting   TabView = "waiting" // Pending tasks whose @start date hasn't arrived
 	TabCompleted TabView = "completed"
 	TabCategory  TabView = "category" // Filtered by specific category
 	TabArchived  TabView = "archived" // Show archived tasks
@@ -48,32 +60,60 @@ const (
 	SortByPriority  SortType = "priority"
 	SortByCreatedAt SortType = "created"
 	SortByCategory  SortType = "category"
+	SortByDue       SortType = "due"
 )
 
+// Tabs are the views cycled through with tab, in order
+var Tabs = []TabView{TabAll, TabToday, TabOverdue, TabUpcoming, TabPending, TabWaiting, TabCompleted}
+
 // Model represents the application state
 type Model struct {
-	Tasks           []Task
-	Cursor          int
-	SelectedTasks   map[int]struct{}
-	InputMode       bool
-	Input           string
-	InputCursor     int // Position of cursor within input field
-	Categories      map[string]struct{}
-	CurrentFilter   string  // Category filter
-	CurrentView     TabView // Current tab view
-	CurrentCategory string  // When in TabCategory
-	Width           int
-	Height          int
-	Pagination      Pagination
-	StatusMessage   string // Temporary status messages
-	EditingTask     bool   // Whether currently editing a task
-	EditingTaskIdx  int    // Index of task being edited
-	HelpVisible     bool   // Whether help is visible
-	TaskExpanded    bool   // Whether task details are expanded
-	ExpandedTaskIdx int    // Index of task being expanded
-	DeleteConfirm   bool   // Whether delete confirmation is active
-	LastDeleted     *Task  // Last deleted task for undo
-	LastDeletedIdx  int    // Index where the task was deleted
+	Tasks              []Task
+	Cursor             int
+	SelectedTasks      map[string]struct{} // IDs of the tasks marked for bulk actions
+	SelectionAnchor    string              // Task marked last, where a range selection starts
+	BulkPrompt         string              // Bulk action waiting for a key ("priority", "complete-blocked", "complete-subtasks") or input ("category", "tags")
+	InputMode          bool
+	Input              string
+	InputCursor        int // Position of cursor within input field
+	Categories         map[string]struct{}
+	CurrentFilter      string  // Category filter
+	CompletedWindow    string  // Only show tasks completed within this window, e.g. "this week"
+	Query              *Query  // Only show tasks matching this filter expression; nil shows all
+	EditingQuery       bool    // Whether the input form is the query bar
+	Search             string  // Fuzzy search narrowing the list; empty for none
+	SearchMode         bool    // Whether keys are typed into the search
+	CurrentView        TabView // Current tab view
+	CurrentCategory    string  // When in TabCategory
+	Width              int
+	Height             int
+	Pagination         Pagination
+	StatusMessage      string          // Temporary status messages
+	EditingTask        bool            // Whether currently editing a task
+	EditingTaskID      string          // ID of task being edited
+	HelpVisible        bool            // Whether help is visible
+	TaskExpanded       bool            // Whether task details are expanded
+	ExpandedTaskID     string          // ID of task being expanded
+	ExpandedHistory    []Event         // Activity log of the expanded task, oldest first
+	DeleteConfirm      bool            // Whether delete confirmation is active
+	History            History         // Changes that can be undone and redone
+	CurrentSort        SortType        // Sort applied most recently
+	Dirty              bool            // Whether there are changes not yet written to disk
+	QuitConfirm        bool            // Whether quitting with unsaved changes is pending confirmation
+	Conflicts          []Conflict      // Unresolved conflicts with changes made on disk
+	BackupsVisible     bool            // Whether the backup browser is open
+	Backups            []Backup        // Backups shown in the browser, newest first
+	BackupCursor       int             // Selected backup in the browser
+	BackupOpen         bool            // Whether the selected backup's tasks are shown
+	BackupTaskIdx      int             // Selected task within the open backup
+	RestoreConfirm     bool            // Whether restoring the selected backup is pending confirmation
+	Collapsed          map[string]bool // Tasks whose subtasks are hidden
+	InputParentID      string          // Task the task being added becomes a subtask of
+	CompleteSubtasksID string          // Completed task whose pending subtasks may be completed too
+	NotesEditor        *NotesEditor    // Open editor for a task's notes, if any
+	NextOccurrenceID   string          // Task created when the last toggled task recurred
+	SnoozeID           string          // Task whose snooze options are shown
+	CompleteBlockedID  string          // Blocked task waiting for confirmation before it is completed
 }
 
 // Pagination tracks position in a paginated list
@@ -106,7 +146,8 @@ func NewModel(tasks []Task) Model {
 
 	return Model{
 		Tasks:         tasks,
-		SelectedTasks: make(map[int]struct{}),
+		SelectedTasks: make(map[string]struct{}),
+		Collapsed:     make(map[string]bool),
 		InputMode:     false,
 		Categories:    categories,
 		CurrentFilter: "", // Empty string means no filter
@@ -155,14 +196,31 @@ func (m Model) GetFilteredTasks() []Task {
 	// First apply tab view filter
 	switch m.CurrentView {
 	case TabAll:
-		// Show all tasks including archived
-		filteredTasks = m.Tasks
-	case TabToday:
-		// TODO: Implement date filtering when we add due dates
-		filteredTasks = m.Tasks
+		// Show all tasks including archived, except those waiting to start
+		waiting := m.waitingIDs(time.Now())
+		for _, task := range m.Tasks {
+			if !waiting[task.ID] {
+				filteredTasks = append(filteredTasks, task)
+			}
+		}
+	case TabToday, TabOverdue, TabUpcoming:
+		now := time.Now()
+		for _, task := range m.Tasks {
+			if task.inDueView(m.CurrentView, now) {
+				filteredTasks = append(filteredTasks, task)
+			}
+		}
 	case TabPending:
+		waiting := m.waitingIDs(time.Now())
+		for _, task := range m.Tasks {
+			if !task.Done && !waiting[task.ID] {
+				filteredTasks = append(filteredTasks, task)
+			}
+		}
+	case TabWaiting:
+		waiting := m.waitingIDs(time.Now())
 		for _, task := range m.Tasks {
-			if !task.Done {
+			if !task.Done && waiting[task.ID] {
 				filteredTasks = append(filteredTasks, task)
 			}
 		}
@@ -197,7 +255,44 @@ func (m Model) GetFilteredTasks() []Task {
 		filteredTasks = tasksWithCategory
 	}
 
-	return filteredTasks
+	// Then keep only recently completed tasks if asked to
+	if m.CompletedWindow != "" {
+		now := time.Now()
+		var completed []Task
+		for _, task := range filteredTasks {
+			if task.CompletedWithin(m.CompletedWindow, now) {
+				completed = append(completed, task)
+			}
+		}
+		filteredTasks = completed
+	}
+
+	// Then keep only the tasks matching the query, if there is one
+	if m.Query != nil {
+		now := time.Now()
+		blocked, waiting := m.blockedIDs(), m.waitingIDs(now)
+		var matching []Task
+		for _, task := range filteredTasks {
+			if m.Query.Match(task, now, blocked, waiting) {
+				matching = append(matching, task)
+			}
+		}
+		filteredTasks = matching
+	}
+
+	// Then keep only the tasks matching the search
+	if m.Search != "" {
+		var matching []Task
+		for _, task := range filteredTasks {
+			if task.MatchesSearch(m.Search) {
+				matching = append(matching, task)
+			}
+		}
+		filteredTasks = matching
+	}
+
+	// Subtasks follow their parent
+	return treeOrder(filteredTasks, m.Collapsed)
 }
 
 // GetVisibleTasks returns only the tasks that should be displayed on the current page
@@ -254,7 +349,7 @@ func (m *Model) CycleCategory() {
 
 // CycleTab changes to the next tab view
 func (m *Model) CycleTab() {
-	tabs := []TabView{TabAll, TabPending, TabCompleted}
+	tabs := Tabs
 
 	// Find current tab position
 	currentIndex := 0
@@ -276,7 +371,9 @@ func (m *Model) CycleTab() {
 
 // CyclePriority cycles through priority levels for the current task
 func (m *Model) CyclePriority() {
-	if len(m.Tasks) == 0 || m.Cursor >= len(m.Tasks) {
+	id := m.CurrentTaskID()
+	idx := m.TaskIndexByID(id)
+	if idx < 0 {
 		return
 	}
 
@@ -285,7 +382,7 @@ func (m *Model) CyclePriority() {
 	// Find current priority position
 	currentIndex := 0
 	for i, priority := range priorities {
-		if priority == m.Tasks[m.Cursor].Priority {
+		if priority == m.Tasks[idx].Priority {
 			currentIndex = i
 			break
 		}
@@ -293,141 +390,220 @@ func (m *Model) CyclePriority() {
 
 	// Cycle to next priority
 	nextIndex := (currentIndex + 1) % len(priorities)
-	m.Tasks[m.Cursor].Priority = priorities[nextIndex]
+	m.Tasks[idx].Priority = priorities[nextIndex]
 
 	// Auto-sort by priority to update UI immediately
 	m.SortTasks(SortByPriority)
 
-	// Find the task in the new sorted position and update cursor
-	taskToFind := m.Tasks[m.Cursor]
-	for i, task := range m.Tasks {
-		if task.Description == taskToFind.Description &&
-			task.Category == taskToFind.Category &&
-			task.CreatedAt == taskToFind.CreatedAt {
-			m.Cursor = i
-			break
-		}
-	}
-
-	// Recalculate pagination after sorting
+	// Recalculate pagination after sorting and keep the cursor on the task
 	m.recalculatePagination()
+	m.SelectTask(id)
 }
 
 // AddTask adds a new task to the model
 func (m *Model) AddTask(description, category string, priority Priority) {
+	m.AddTaskWithMetadata(description, category, priority, nil)
+}
+
+// AddTaskWithMetadata adds a new task carrying the given @key:value metadata
+func (m *Model) AddTaskWithMetadata(description, category string, priority Priority, fields []MetadataField) {
 	if category != "" {
 		m.Categories[category] = struct{}{}
 	}
 
-	// Default to low priority if none specified
+	m.Tasks = append(m.Tasks, m.newTask(description, category, priority, fields))
+
+	// Auto-sort by priority to ensure proper positioning
+	m.SortTasks(SortByPriority)
+}
+
+// newTask creates a task with a fresh ID, defaulting to low priority. When
+// tasks from several files are shown it goes to the file of the task under
+// the cursor.
+func (m *Model) newTask(description, category string, priority Priority, fields []MetadataField) Task {
 	if priority == PriorityNone {
 		priority = PriorityLow
 	}
 
-	m.Tasks = append(m.Tasks, Task{
+	task := Task{
+		ID:          m.uniqueTaskID(),
 		Description: description,
 		Category:    category,
 		Priority:    priority,
 		CreatedAt:   time.Now(),
-	})
+	}
+	if idx := m.TaskIndexByID(m.CurrentTaskID()); idx >= 0 {
+		task.Source = m.Tasks[idx].Source
+	}
+	task.ApplyMetadata(fields)
+	return task
+}
+
+// UpdateTask updates an existing task. Any metadata fields are added to the
+// task's existing metadata, replacing values of the same key.
+func (m *Model) UpdateTask(id string, description, category string, priority Priority, fields []MetadataField) {
+	index := m.TaskIndexByID(id)
+	if index < 0 {
+		return
+	}
+
+	m.setCategory(index, category)
+	m.Tasks[index].Description = description
+	m.Tasks[index].Priority = priority
+	m.Tasks[index].ApplyMetadata(fields)
 
 	// Auto-sort by priority to ensure proper positioning
 	m.SortTasks(SortByPriority)
 }
 
-// UpdateTask updates an existing task
-func (m *Model) UpdateTask(index int, description, category string, priority Priority) {
-	if index < 0 || index >= len(m.Tasks) {
+// setCategory moves the task at index to another category. A subtask moved
+// to another category leaves its parent, and takes its own subtasks along.
+func (m *Model) setCategory(index int, category string) {
+	if category == m.Tasks[index].Category {
 		return
 	}
-
-	if category != "" && category != m.Tasks[index].Category {
+	if category != "" {
 		m.Categories[category] = struct{}{}
 	}
 
-	m.Tasks[index].Description = description
+	m.Tasks[index].ParentID = ""
+	for _, childID := range m.descendantIDs(m.Tasks[index].ID) {
+		m.Tasks[m.TaskIndexByID(childID)].Category = category
+	}
 	m.Tasks[index].Category = category
-	m.Tasks[index].Priority = priority
-
-	// Auto-sort by priority to ensure proper positioning
-	m.SortTasks(SortByPriority)
 }
 
 // DeleteCurrentTask deletes the task at the current cursor position
 func (m *Model) DeleteCurrentTask() {
 	filteredTasks := m.GetVisibleTasks()
-	if len(filteredTasks) == 0 || m.Cursor >= len(filteredTasks) {
+	if !m.DeleteTask(m.CurrentTaskID()) {
 		return
 	}
 
-	// Find the actual index in the Tasks slice
-	taskToDelete := filteredTasks[m.Cursor]
-	var taskIdx int
-	found := false
-
-	for i, task := range m.Tasks {
-		if task.Description == taskToDelete.Description &&
-			task.Category == taskToDelete.Category &&
-			task.CreatedAt == taskToDelete.CreatedAt {
-			taskIdx = i
-			found = true
-			break
-		}
+	// Update cursor if needed
+	if m.Cursor >= len(filteredTasks)-1 && m.Cursor > 0 {
+		m.Cursor--
 	}
+}
 
-	if !found {
-		return // Task not found in the main list
+// DeleteTask deletes the task with the given ID. Its subtasks move up to
+// take its place.
+func (m *Model) DeleteTask(id string) bool {
+	taskIdx := m.TaskIndexByID(id)
+	if taskIdx < 0 {
+		return false // Task not found in the main list
 	}
 
-	// Store the deleted task for potential undo
 	deletedTask := m.Tasks[taskIdx]
-	m.LastDeleted = &deletedTask
-	m.LastDeletedIdx = taskIdx
-
-	// Remove the task
 	m.Tasks = append(m.Tasks[:taskIdx], m.Tasks[taskIdx+1:]...)
 
-	// Update cursor if needed
-	if m.Cursor >= len(filteredTasks)-1 && m.Cursor > 0 {
-		m.Cursor--
+	for i := range m.Tasks {
+		if m.Tasks[i].ParentID == id {
+			m.Tasks[i].ParentID = deletedTask.ParentID
+		}
 	}
+	return true
 }
 
-// UndoDelete restores the last deleted task
-func (m *Model) UndoDelete() bool {
-	if m.LastDeleted == nil {
-		return false
+// RemoveTasks takes the tasks with the given IDs out of the list, e.g. after
+// they were moved to another file
+func (m *Model) RemoveTasks(ids []string) {
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
 	}
 
-	// Calculate insertion point (either the original position or end of list)
-	insertIdx := m.LastDeletedIdx
-	if insertIdx > len(m.Tasks) {
-		insertIdx = len(m.Tasks)
+	kept := m.Tasks[:0]
+	for _, task := range m.Tasks {
+		if !remove[task.ID] {
+			kept = append(kept, task)
+		}
 	}
+	m.Tasks = kept
 
-	// Insert task back into original position
-	if insertIdx == len(m.Tasks) {
-		m.Tasks = append(m.Tasks, *m.LastDeleted)
-	} else {
-		// Create space and insert the task
-		m.Tasks = append(m.Tasks, Task{})                // Add empty task at end
-		copy(m.Tasks[insertIdx+1:], m.Tasks[insertIdx:]) // Shift tasks right
-		m.Tasks[insertIdx] = *m.LastDeleted              // Insert task
+	m.recalculatePagination()
+	if visible := len(m.GetVisibleTasks()); m.Cursor >= visible {
+		m.Cursor = max(visible-1, 0)
 	}
-
-	// Clean up
-	m.LastDeleted = nil
-	m.RecalculatePagination()
-	return true
 }
 
 // ToggleCurrentTask toggles the completion status of the current task
 func (m *Model) ToggleCurrentTask() {
-	if len(m.Tasks) == 0 || m.Cursor >= len(m.Tasks) {
-		return
+	m.ToggleTask(m.CurrentTaskID())
+}
+
+// ToggleTask toggles the completion status of the task with the given ID.
+// Completing a task with an @every rule adds its next occurrence; see
+// NextOccurrenceID.
+func (m *Model) ToggleTask(id string) bool {
+	idx := m.TaskIndexByID(id)
+	if idx < 0 {
+		return false
+	}
+
+	m.NextOccurrenceID = ""
+	if m.Tasks[idx].Done {
+		m.Tasks[idx].SetDone(false)
+	} else {
+		m.NextOccurrenceID = m.completeRecurring(idx, time.Now())
+	}
+	return true
+}
+
+// ReplaceTasks swaps in a new task list, e.g. after the file was reloaded,
+// keeping the current sort and the cursor on the same task where possible
+func (m *Model) ReplaceTasks(tasks []Task) {
+	id := m.CurrentTaskID()
+
+	m.Tasks = tasks
+	for _, task := range tasks {
+		if task.Category != "" {
+			m.Categories[task.Category] = struct{}{}
+		}
+	}
+
+	if m.CurrentSort != "" {
+		m.SortTasks(m.CurrentSort)
+	}
+	m.recalculatePagination()
+
+	if !m.SelectTask(id) {
+		// The task is gone; stay at the same position, within bounds
+		if visible := len(m.GetVisibleTasks()); m.Cursor >= visible {
+			m.Cursor = max(visible-1, 0)
+		}
+	}
+}
+
+// OpenFile replaces the tasks with those of another task file. Unlike
+// ReplaceTasks it starts afresh, with the new file's categories, no category
+// filter, nothing folded and nothing to undo.
+func (m *Model) OpenFile(tasks []Task) {
+	m.Categories = make(map[string]struct{})
+	m.Collapsed = make(map[string]bool)
+	m.CurrentFilter = ""
+	if m.CurrentView == TabCategory {
+		m.CurrentView = TabAll
 	}
+	m.CurrentCategory = ""
+	m.ClearHistory()
+	m.ClearMarks()
+	m.Dirty = false
+
+	m.Cursor = 0
+	m.Pagination.Page = 0
+	m.ReplaceTasks(tasks)
+}
 
-	m.Tasks[m.Cursor].Done = !m.Tasks[m.Cursor].Done
+// HasSources reports whether the tasks come from several files, as with
+// --tree, so the list should say where each one came from
+func (m Model) HasSources() bool {
+	for _, task := range m.Tasks {
+		if task.Source != "" {
+			return true
+		}
+	}
+	return false
 }
 
 // ToggleHelp shows or hides the help screen
@@ -534,52 +710,44 @@ func (m *Model) DeleteTextBeforeCursor() {
 
 // ArchiveCurrentTask archives the task at the current cursor position
 func (m *Model) ArchiveCurrentTask() {
-	filteredTasks := m.GetVisibleTasks()
-	if len(filteredTasks) == 0 || m.Cursor >= len(filteredTasks) {
-		return
-	}
-
-	// Find the actual index in the Tasks slice
-	taskToArchive := filteredTasks[m.Cursor]
-	var taskIdx int
-	found := false
-
-	for i, task := range m.Tasks {
-		if task.Description == taskToArchive.Description &&
-			task.Category == taskToArchive.Category &&
-			task.CreatedAt == taskToArchive.CreatedAt {
-			taskIdx = i
-			found = true
-			break
-		}
-	}
+	m.ArchiveTask(m.CurrentTaskID())
+}
 
-	if !found {
-		return // Task not found in the main list
+// ArchiveTask archives the task with the given ID
+func (m *Model) ArchiveTask(id string) bool {
+	idx := m.TaskIndexByID(id)
+	if idx < 0 {
+		return false
 	}
 
-	// Archive the task
-	m.Tasks[taskIdx].Archived = true
+	m.Tasks[idx].Archived = true
 
 	// Recalculate pagination after archiving
 	m.recalculatePagination()
+	return true
 }
 
-// UnarchiveTask unarchives a task by its index
-func (m *Model) UnarchiveTask(index int) {
-	if index < 0 || index >= len(m.Tasks) {
-		return
+// UnarchiveTask unarchives the task with the given ID
+func (m *Model) UnarchiveTask(id string) bool {
+	idx := m.TaskIndexByID(id)
+	if idx < 0 {
+		return false
 	}
-	m.Tasks[index].Archived = false
+
+	m.Tasks[idx].Archived = false
 	m.recalculatePagination()
+	return true
 }
 
-// getTaskStatus returns a numeric status for sorting (lower = higher priority)
-func getTaskStatus(task Task) int {
+// getTaskStatus returns a numeric status for sorting (lower = higher priority).
+// Tasks waiting for others to be done sort below the ones that can be worked on.
+func getTaskStatus(task Task, blocked map[string]bool) int {
 	if task.Archived {
-		return 2 // Archived tasks (middle priority)
+		return 3 // Archived tasks, below the blocked ones
 	} else if task.Done {
-		return 3 // Completed tasks (lowest priority)
+		return 4 // Completed tasks (lowest priority)
+	} else if blocked[task.ID] {
+		return 2 // Blocked tasks, below the active ones
 	}
 	return 1 // Active tasks (highest priority)
 }
@@ -678,12 +846,15 @@ func (m *Model) SetStatus(message string) {
 
 // SortTasks sorts the tasks based on the specified sort type
 func (m *Model) SortTasks(sortType SortType) {
+	m.CurrentSort = sortType
+	blocked := m.blockedIDs()
+
 	switch sortType {
 	case SortByPriority:
 		sort.SliceStable(m.Tasks, func(i, j int) bool {
-			// First sort by status: Active > Archived > Completed
-			statusI := getTaskStatus(m.Tasks[i])
-			statusJ := getTaskStatus(m.Tasks[j])
+			// First sort by status: Active > Blocked > Archived > Completed
+			statusI := getTaskStatus(m.Tasks[i], blocked)
+			statusJ := getTaskStatus(m.Tasks[j], blocked)
 			if statusI != statusJ {
 				return statusI < statusJ // Lower status number = higher priority
 			}
@@ -692,9 +863,9 @@ func (m *Model) SortTasks(sortType SortType) {
 		})
 	case SortByCreatedAt:
 		sort.SliceStable(m.Tasks, func(i, j int) bool {
-			// First sort by status: Active > Archived > Completed
-			statusI := getTaskStatus(m.Tasks[i])
-			statusJ := getTaskStatus(m.Tasks[j])
+			// First sort by status: Active > Blocked > Archived > Completed
+			statusI := getTaskStatus(m.Tasks[i], blocked)
+			statusJ := getTaskStatus(m.Tasks[j], blocked)
 			if statusI != statusJ {
 				return statusI < statusJ // Lower status number = higher priority
 			}
@@ -703,14 +874,25 @@ func (m *Model) SortTasks(sortType SortType) {
 		})
 	case SortByCategory:
 		sort.SliceStable(m.Tasks, func(i, j int) bool {
-			// First sort by status: Active > Archived > Completed
-			statusI := getTaskStatus(m.Tasks[i])
-			statusJ := getTaskStatus(m.Tasks[j])
+			// First sort by status: Active > Blocked > Archived > Completed
+			statusI := getTaskStatus(m.Tasks[i], blocked)
+			statusJ := getTaskStatus(m.Tasks[j], blocked)
 			if statusI != statusJ {
 				return statusI < statusJ // Lower status number = higher priority
 			}
 			// Within same status group, sort by category
 			return m.Tasks[i].Category < m.Tasks[j].Category
 		})
+	case SortByDue:
+		sort.SliceStable(m.Tasks, func(i, j int) bool {
+			// First sort by status: Active > Blocked > Archived > Completed
+			statusI := getTaskStatus(m.Tasks[i], blocked)
+			statusJ := getTaskStatus(m.Tasks[j], blocked)
+			if statusI != statusJ {
+				return statusI < statusJ // Lower status number = higher priority
+			}
+			// Within same status group, sort by due date
+			return dueBefore(m.Tasks[i], m.Tasks[j])
+		})
 	}
 }
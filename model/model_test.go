@@ -0,0 +1,755 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestModel() Model {
+	created := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	return NewModel([]Task{
+		{ID: "aaaaaa", Description: "Done task", Category: "Work", Done: true, CreatedAt: created},
+		{ID: "bbbbbb", Description: "Same", Category: "Work", CreatedAt: created},
+		{ID: "cccccc", Description: "Same", Category: "Work", CreatedAt: created},
+	})
+}
+
+func TestCursorActionsUseTaskUnderCursor(t *testing.T) {
+	m := newTestModel()
+	m.CurrentView = TabPending
+	m.Cursor = 1 // Second pending task, third task overall
+
+	m.CyclePriority()
+	if m.Tasks[m.TaskIndexByID("cccccc")].Priority != PriorityMedium {
+		t.Errorf("Expected task under cursor to change priority, tasks: %+v", m.Tasks)
+	}
+	if m.Tasks[m.TaskIndexByID("bbbbbb")].Priority != PriorityNone {
+		t.Errorf("Identical task was changed too: %+v", m.Tasks)
+	}
+	if m.CurrentTaskID() != "cccccc" {
+		t.Errorf("Expected cursor to follow the task, got %q", m.CurrentTaskID())
+	}
+
+	m.ToggleCurrentTask()
+	if !m.Tasks[m.TaskIndexByID("cccccc")].Done || m.Tasks[m.TaskIndexByID("bbbbbb")].Done {
+		t.Errorf("Toggle hit the wrong task: %+v", m.Tasks)
+	}
+}
+
+func TestDeleteAndArchiveByID(t *testing.T) {
+	m := newTestModel()
+
+	if !m.ArchiveTask("bbbbbb") || !m.Tasks[m.TaskIndexByID("bbbbbb")].Archived {
+		t.Fatalf("Expected task to be archived")
+	}
+	if m.Tasks[m.TaskIndexByID("cccccc")].Archived {
+		t.Errorf("Identical task was archived too")
+	}
+
+	if !m.DeleteTask("cccccc") {
+		t.Fatalf("Expected task to be deleted")
+	}
+	if m.TaskIndexByID("cccccc") >= 0 || m.TaskIndexByID("bbbbbb") < 0 {
+		t.Errorf("Wrong task deleted: %+v", m.Tasks)
+	}
+}
+
+func TestAddTaskAssignsUniqueID(t *testing.T) {
+	m := newTestModel()
+	m.AddTask("New", "Work", PriorityHigh)
+	m.AddTask("New", "Work", PriorityHigh)
+
+	seen := make(map[string]bool)
+	for _, task := range m.Tasks {
+		if task.ID == "" || seen[task.ID] {
+			t.Fatalf("Expected unique non-empty IDs, got %+v", m.Tasks)
+		}
+		seen[task.ID] = true
+	}
+}
+
+func TestMergeTasks(t *testing.T) {
+	created := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	task := func(id, description string) Task {
+		return Task{ID: id, Description: description, Category: "Work", CreatedAt: created}
+	}
+
+	base := []Task{task("a", "Keep"), task("b", "Edited outside"), task("c", "Edited here"), task("d", "Deleted outside"), task("e", "Deleted here")}
+	theirs := []Task{task("a", "Keep"), task("b", "Edited outside, new"), task("c", "Edited here"), task("e", "Deleted here"), task("f", "Added outside")}
+	ours := []Task{task("a", "Keep"), task("b", "Edited outside"), task("c", "Edited here, new"), task("d", "Deleted outside"), task("g", "Added here")}
+
+	merged, conflicts := MergeTasks(base, theirs, ours)
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %+v", conflicts)
+	}
+
+	var got []string
+	for _, task := range merged {
+		got = append(got, task.ID+":"+task.Description)
+	}
+	want := []string{"a:Keep", "b:Edited outside, new", "c:Edited here, new", "g:Added here", "f:Added outside"}
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestMergeTasksFieldConflicts(t *testing.T) {
+	created := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	base := Task{ID: "a", Description: "Write report", Category: "Work", Priority: PriorityLow, CreatedAt: created}
+
+	theirs := base.Clone()
+	theirs.Description = "Write the quarterly report"
+	theirs.Done = true
+	theirs.SetMetadata("due", "2025-03-20")
+
+	ours := base.Clone()
+	ours.Description = "Write report draft"
+	ours.Priority = PriorityHigh
+
+	merged, conflicts := MergeTasks([]Task{base}, []Task{theirs}, []Task{ours})
+	if len(merged) != 1 {
+		t.Fatalf("Expected one task, got %+v", merged)
+	}
+
+	// Fields changed on one side only are merged
+	got := merged[0]
+	if !got.Done || got.Priority != PriorityHigh || got.Metadata["due"] != "2025-03-20" {
+		t.Errorf("Expected non-conflicting changes from both sides, got %+v", got)
+	}
+
+	// The description was changed on both sides
+	if len(conflicts) != 1 || conflicts[0].Field != "description" {
+		t.Fatalf("Expected a description conflict, got %+v", conflicts)
+	}
+	if got.Description != "Write report draft" {
+		t.Errorf("Expected our description until resolved, got %q", got.Description)
+	}
+
+	m := NewModel(merged)
+	m.ResolveConflict(conflicts[0], true)
+	if m.Tasks[0].Description != "Write the quarterly report" || m.Tasks[0].Priority != PriorityHigh {
+		t.Errorf("Expected their description and our priority, got %+v", m.Tasks[0])
+	}
+}
+
+func TestMergeTasksDeleteConflict(t *testing.T) {
+	created := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	base := Task{ID: "a", Description: "Call mom", CreatedAt: created}
+	ours := base.Clone()
+	ours.Done = true
+
+	merged, conflicts := MergeTasks([]Task{base}, nil, []Task{ours})
+	if len(merged) != 1 || len(conflicts) != 1 || conflicts[0].Theirs != deletedValue {
+		t.Fatalf("Expected the edited task to be kept with a conflict, got %+v %+v", merged, conflicts)
+	}
+
+	m := NewModel(merged)
+	m.ResolveConflict(conflicts[0], true)
+	if len(m.Tasks) != 0 {
+		t.Errorf("Expected taking their side to delete the task, got %+v", m.Tasks)
+	}
+}
+
+func TestDiffTasks(t *testing.T) {
+	backup := []Task{
+		{ID: "a", Description: "Same"},
+		{ID: "b", Description: "Finished since"},
+		{ID: "c", Description: "Removed since"},
+	}
+	current := []Task{
+		{ID: "a", Description: "Same"},
+		{ID: "b", Description: "Finished since", Done: true},
+		{ID: "d", Description: "Added since"},
+	}
+
+	diff := DiffTasks(backup, current)
+	if len(diff.Added) != 1 || diff.Added[0].ID != "d" {
+		t.Errorf("Expected d to be added, got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].ID != "c" {
+		t.Errorf("Expected c to be removed, got %+v", diff.Removed)
+	}
+	if len(diff.Completed) != 1 || diff.Completed[0].ID != "b" {
+		t.Errorf("Expected b to be completed, got %+v", diff.Completed)
+	}
+	if len(diff.Changed) != 0 || len(diff.Reopened) != 0 {
+		t.Errorf("Expected nothing else, got %+v", diff)
+	}
+}
+
+func TestSubtasksFollowParentAndFold(t *testing.T) {
+	m := NewModel([]Task{
+		{ID: "c1", Description: "Child", ParentID: "p", Priority: PriorityHigh},
+		{ID: "o", Description: "Other", Priority: PriorityMedium},
+		{ID: "p", Description: "Parent", Priority: PriorityLow},
+		{ID: "c2", Description: "Done child", ParentID: "p", Done: true},
+	})
+	m.Pagination.ItemsPerPage = 0
+	m.SortTasks(SortByPriority)
+
+	var order []string
+	for _, task := range m.GetFilteredTasks() {
+		order = append(order, task.ID)
+	}
+	if strings.Join(order, ",") != "o,p,c1,c2" {
+		t.Errorf("Expected subtasks below their parent, got %v", order)
+	}
+
+	if done, total := m.SubtaskProgress("p"); done != 1 || total != 2 {
+		t.Errorf("Expected 1/2 subtasks done, got %d/%d", done, total)
+	}
+
+	m.ToggleCollapsed("p")
+	if n := len(m.GetFilteredTasks()); n != 2 {
+		t.Errorf("Expected folded subtasks to be hidden, got %d tasks", n)
+	}
+
+	if n := m.CompleteSubtasks("p"); n != 1 || m.PendingSubtasks("p") != 0 {
+		t.Errorf("Expected the pending subtask to be completed, got %d", n)
+	}
+}
+
+func TestNotesEditor(t *testing.T) {
+	m := NewModel([]Task{{ID: "a", Description: "Task", Notes: "First line"}})
+	if !m.OpenNotesEditor("a") {
+		t.Fatal("Expected the editor to open")
+	}
+
+	e := m.NotesEditor
+	e.Newline()
+	e.Insert("Zweite Zeile ü")
+	e.Backspace()
+	e.Move(-1, 0)
+	e.Home()
+	e.Insert("> ")
+	e.Move(1, 0)
+	e.End()
+	e.Insert("\n\n")
+
+	if !m.SaveNotes() || m.NotesEditor != nil {
+		t.Fatal("Expected the notes to be saved and the editor closed")
+	}
+	if want := "> First line\nZweite Zeile"; m.Tasks[0].Notes != want {
+		t.Errorf("Expected notes %q, got %q", want, m.Tasks[0].Notes)
+	}
+}
+
+func TestCompletionTimeAndWindows(t *testing.T) {
+	var task Task
+	task.SetDone(true)
+	if task.CompletedAt.IsZero() {
+		t.Fatal("Expected completing a task to record when")
+	}
+	task.SetDone(false)
+	if !task.CompletedAt.IsZero() {
+		t.Error("Expected reopening a task to clear its completion time")
+	}
+
+	// Thursday afternoon
+	now := time.Date(2025, 3, 13, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		completed time.Time
+		window    string
+		want      bool
+	}{
+		{time.Date(2025, 3, 13, 9, 0, 0, 0, time.UTC), "today", true},
+		{time.Date(2025, 3, 12, 23, 0, 0, 0, time.UTC), "today", false},
+		{time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), "this week", true},
+		{time.Date(2025, 3, 9, 23, 59, 0, 0, time.UTC), "this week", false},
+		{time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC), "last 7 days", true},
+		{time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), "last 30 days", false},
+		{time.Time{}, "last 30 days", false},
+	}
+	for _, tt := range tests {
+		task := Task{Done: true, CompletedAt: tt.completed}
+		if got := task.CompletedWithin(tt.window, now); got != tt.want {
+			t.Errorf("CompletedWithin(%q) for %v = %v, want %v", tt.window, tt.completed, got, tt.want)
+		}
+	}
+}
+
+func TestRecurringTasks(t *testing.T) {
+	// Thursday
+	now := time.Date(2025, 3, 13, 15, 0, 0, 0, time.Local)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.Local) }
+
+	tests := []struct {
+		rule string
+		due  time.Time
+		want time.Time
+	}{
+		{"weekly", day(2025, 3, 13), day(2025, 3, 20)},
+		{"3d", day(2025, 3, 14), day(2025, 3, 17)},
+		{"daily", day(2025, 3, 1), day(2025, 3, 14)}, // Completed late, next is still ahead
+		{"monthly", day(2025, 1, 31), day(2025, 3, 31)},
+		{"monthly", day(2025, 3, 31), day(2025, 4, 30)},
+		{"mon,thu", day(2025, 3, 13), day(2025, 3, 17)},
+		{"weekdays", day(2025, 3, 7), day(2025, 3, 14)},
+		{"1y", day(2024, 2, 29), day(2026, 2, 28)},
+	}
+	for _, tt := range tests {
+		r, err := ParseRecurrence(tt.rule)
+		if err != nil {
+			t.Fatalf("ParseRecurrence(%q) failed: %v", tt.rule, err)
+		}
+		if got := r.Next(tt.due, now); !got.Equal(tt.want) {
+			t.Errorf("@every:%s from %s: next is %s, want %s", tt.rule, tt.due.Format(DateLayout), got.Format(DateLayout), tt.want.Format(DateLayout))
+		}
+	}
+	if _, err := ParseRecurrence("fortnightly-ish"); err == nil {
+		t.Error("Expected an unknown rule to be rejected")
+	}
+
+	// Completing a recurring task adds the next occurrence and moves the rule
+	m := NewModel([]Task{{ID: "aaaaaa", Description: "Bump deps", Category: "Chores"}})
+	m.Tasks[0].SetMetadata("due", "2099-01-05")
+	m.Tasks[0].SetMetadata("every", "weekly")
+	if !m.ToggleTask("aaaaaa") || len(m.Tasks) != 2 {
+		t.Fatalf("Expected a next occurrence, got %+v", m.Tasks)
+	}
+	next := m.Tasks[m.TaskIndexByID(m.NextOccurrenceID)]
+	if next.Done || next.Metadata["due"] != "2099-01-12" || next.Metadata["every"] != "weekly" || next.Category != "Chores" {
+		t.Errorf("Unexpected next occurrence %+v", next)
+	}
+	if done := m.Tasks[0]; !done.Done || done.Metadata["every"] != "" {
+		t.Errorf("Expected the completed task to keep no rule, got %+v", done)
+	}
+
+	// Reopening it doesn't repeat it again
+	m.ToggleTask("aaaaaa")
+	m.ToggleTask("aaaaaa")
+	if len(m.Tasks) != 2 {
+		t.Errorf("Expected no further occurrence, got %d tasks", len(m.Tasks))
+	}
+}
+
+func TestDueViewsAndSort(t *testing.T) {
+	now := time.Now()
+	day := func(offset int) string { return now.AddDate(0, 0, offset).Format(DateLayout) }
+	task := func(id, due string) Task {
+		task := Task{ID: id, Description: id}
+		if due != "" {
+			task.SetMetadata("due", due)
+		}
+		return task
+	}
+
+	m := NewModel([]Task{
+		task("later", day(30)),
+		task("none", ""),
+		task("upcoming", day(3)),
+		task("today", day(0)),
+		task("overdue", day(-2)),
+		task("past-hour", now.Add(-time.Hour).Format(DateTimeLayout)),
+	})
+	m.Tasks = append(m.Tasks, task("done", day(-1)))
+	m.Tasks[len(m.Tasks)-1].Done = true
+
+	ids := func() string {
+		var ids []string
+		for _, task := range m.GetFilteredTasks() {
+			ids = append(ids, task.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	for view, want := range map[TabView]string{
+		TabOverdue:  "overdue,past-hour",
+		TabToday:    "today,overdue,past-hour",
+		TabUpcoming: "upcoming",
+	} {
+		m.CurrentView = view
+		if got := ids(); got != want {
+			t.Errorf("%s tab shows %s, want %s", view, got, want)
+		}
+	}
+
+	m.CurrentView = TabAll
+	m.DeleteTask("past-hour")
+	m.SortTasks(SortByDue)
+	if got := ids(); got != "overdue,today,upcoming,later,none,done" {
+		t.Errorf("Sorted by due date: %s", got)
+	}
+}
+
+func TestResolveDue(t *testing.T) {
+	// Thursday
+	now := time.Date(2025, 3, 13, 15, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"2025-03-14", "2025-03-14"},
+		{"2025-03-14T15:00", "2025-03-14T15:00"},
+		{"today", "2025-03-13"},
+		{"tomorrow", "2025-03-14"},
+		{"tomorrowT9:30", "2025-03-14T09:30"},
+		{"fri", "2025-03-14"},
+		{"Thursday", "2025-03-20"}, // Today's weekday means next week
+		{"+3d", "2025-03-16"},
+		{"+2w", "2025-03-27"},
+		{"+1m", "2025-04-13"},
+		{"next-week", "2025-03-17"},
+		{"next-month", "2025-04-01"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveDue(tt.value, now)
+		if err != nil {
+			t.Errorf("ResolveDue(%q) failed: %v", tt.value, err)
+		} else if got != tt.want {
+			t.Errorf("ResolveDue(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"someday", "+3x", "tomorrowT25:00", "2025-02-30"} {
+		if got, err := ResolveDue(value, now); err == nil {
+			t.Errorf("ResolveDue(%q) = %s, want an error", value, got)
+		}
+	}
+}
+
+func TestWaitingTasksAndSnooze(t *testing.T) {
+	now := time.Now()
+	day := func(offset int) string { return now.AddDate(0, 0, offset).Format(DateLayout) }
+
+	m := NewModel([]Task{
+		{ID: "now", Description: "Ready"},
+		{ID: "later", Description: "Deferred", Metadata: map[string]string{"start": day(2)}},
+		{ID: "child", Description: "Waits with its parent", ParentID: "later"},
+		{ID: "past", Description: "Started", Metadata: map[string]string{"wait": day(-1)}},
+	})
+	ids := func() string {
+		var ids []string
+		for _, task := range m.GetFilteredTasks() {
+			ids = append(ids, task.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	for view, want := range map[TabView]string{
+		TabAll:     "now,past",
+		TabPending: "now,past",
+		TabWaiting: "later,child",
+	} {
+		m.CurrentView = view
+		if got := ids(); got != want {
+			t.Errorf("%s tab shows %s, want %s", view, got, want)
+		}
+	}
+
+	// Snoozing a waiting task pushes its start date back; others start from today
+	later := m.Tasks[m.TaskIndexByID("later")]
+	m.Snooze("later", later.SnoozeDate(7, now))
+	if got := m.Tasks[m.TaskIndexByID("later")].Metadata["start"]; got != day(9) {
+		t.Errorf("Snoozed waiting task starts %s, want %s", got, day(9))
+	}
+	past := m.Tasks[m.TaskIndexByID("past")]
+	m.Snooze("past", past.SnoozeDate(1, now))
+	if got := m.Tasks[m.TaskIndexByID("past")].Metadata["wait"]; got != day(1) {
+		t.Errorf("Snoozed @wait task waits until %s, want %s", got, day(1))
+	}
+}
+
+func TestTimers(t *testing.T) {
+	start := time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+	m := NewModel([]Task{
+		{ID: "aaaaaa", Description: "Write report", Metadata: map[string]string{"estimate": "2h", "spent": "30m"}},
+		{ID: "bbbbbb", Description: "Review"},
+	})
+
+	m.StartTimer("aaaaaa", start)
+	if task, ok := m.RunningTimer(); !ok || task.ID != "aaaaaa" {
+		t.Fatalf("Running timer is on %q, want aaaaaa", task.ID)
+	}
+	if got := m.Tasks[0].TimeSpent(start.Add(15 * time.Minute)); got != 45*time.Minute {
+		t.Errorf("Time spent while running = %s, want 45m", got)
+	}
+
+	// Only one timer runs at a time
+	stopped, _ := m.StartTimer("bbbbbb", start.Add(time.Hour+90*time.Second))
+	if stopped != "aaaaaa" {
+		t.Errorf("Starting a second timer stopped %q, want aaaaaa", stopped)
+	}
+	if got := m.Tasks[0].Metadata["spent"]; got != "1h31m30s" {
+		t.Errorf("@spent after the session = %q, want 1h31m30s", got)
+	}
+	if _, running := m.Tasks[0].TimerStarted(); running {
+		t.Error("The first timer is still running")
+	}
+
+	// Completing a task stops its timer
+	m.ToggleTask("bbbbbb")
+	if _, ok := m.RunningTimer(); ok {
+		t.Error("Timer still running after the task was completed")
+	}
+	if FormatEffort(90*time.Minute+20*time.Second) != "1h30m" {
+		t.Errorf("FormatEffort(1h30m20s) = %s", FormatEffort(90*time.Minute+20*time.Second))
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	m := NewModel([]Task{
+		{ID: "report", Description: "Write report", Metadata: map[string]string{"blocked-by": "data,review"}},
+		{ID: "data", Description: "Collect data"},
+		{ID: "review", Description: "Review", Done: true},
+		{ID: "free", Description: "Unrelated"},
+	})
+
+	if !m.IsBlocked("report") {
+		t.Fatal("Task with an open blocker isn't blocked")
+	}
+	if got := m.Dependants("data"); len(got) != 1 || got[0].ID != "report" {
+		t.Errorf("Dependants of data = %v, want report", got)
+	}
+
+	// Blocked tasks sort below the ones that can be worked on
+	m.SortTasks(SortByPriority)
+	if m.Tasks[2].ID != "report" {
+		t.Errorf("Blocked task sorted at %d, want after the open tasks", m.TaskIndexByID("report"))
+	}
+
+	m.ToggleTask("data")
+	if m.IsBlocked("report") {
+		t.Error("Task still blocked once its blockers are done")
+	}
+
+	if cycles := m.DependencyCycles(); len(cycles) != 0 {
+		t.Errorf("Unexpected cycles %v", cycles)
+	}
+	m.Tasks[m.TaskIndexByID("data")].SetMetadata("after", "report")
+	cycles := m.DependencyCycles()
+	if len(cycles) != 1 || strings.Join(cycles[0], ",") != "data,report,data" {
+		t.Errorf("Cycles = %v, want data,report,data", cycles)
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	m := newTestModel()
+	do := func(status string, change func()) {
+		before := m.Checkpoint()
+		change()
+		m.SetStatus(status)
+		m.Record(before)
+	}
+
+	m.Cursor = 1
+	do("Task marked as complete. Complete its subtasks too? (y/n)", func() { m.ToggleTask("bbbbbb") })
+	m.Cursor = 2
+	do("Task deleted", func() { m.DeleteTask("cccccc") })
+	do("Sorted by category", func() { m.SortTasks(SortByCategory) }) // No change, not recorded
+
+	label, ok := m.Undo()
+	if !ok || label != "Task deleted" || len(m.Tasks) != 3 || m.Cursor != 2 {
+		t.Fatalf("Undo = %q, %v with %d tasks and the cursor at %d", label, ok, len(m.Tasks), m.Cursor)
+	}
+	label, ok = m.Undo()
+	if !ok || label != "Task marked as complete" || m.Tasks[1].Done || m.Cursor != 1 {
+		t.Fatalf("Second undo = %q, %v, done %v, cursor %d", label, ok, m.Tasks[1].Done, m.Cursor)
+	}
+	if _, ok := m.Undo(); ok {
+		t.Error("Undid more changes than were made")
+	}
+
+	if label, ok := m.Redo(); !ok || label != "Task marked as complete" || !m.Tasks[1].Done {
+		t.Errorf("Redo = %q, %v", label, ok)
+	}
+
+	// A new change drops what was undone
+	do("Task archived", func() { m.ArchiveTask("aaaaaa") })
+	if m.CanRedo() {
+		t.Error("Redo still possible after a new change")
+	}
+
+	// Tasks changed some other way since can't be undone
+	m.Tasks[2].Description = "Changed on disk"
+	if _, ok := m.Undo(); ok || m.CanUndo() {
+		t.Error("Undo applied over tasks changed since")
+	}
+}
+
+func TestBulkActions(t *testing.T) {
+	m := newTestModel()
+	m.Tasks = append(m.Tasks, Task{ID: "dddddd", Description: "Other", Category: "Home", Metadata: map[string]string{"tags": "later,home"}})
+	m.CurrentView = TabPending
+
+	m.ToggleMark("bbbbbb")
+	if n := m.MarkRange("dddddd"); n != 3 {
+		t.Fatalf("MarkRange marked %d tasks, want 3", n)
+	}
+	if m.ToggleMark("cccccc") || len(m.MarkedIDs()) != 2 {
+		t.Fatalf("ToggleMark didn't unmark, marked: %v", m.MarkedIDs())
+	}
+	if n := m.MarkAll(); n != 3 || m.IsMarked("aaaaaa") {
+		t.Fatalf("MarkAll marked %d tasks: %v", n, m.MarkedIDs())
+	}
+
+	ids := m.MarkedIDs()
+	m.SetPriority(ids, PriorityHigh)
+	m.SetCategory(ids, "Errands")
+	m.ChangeTags(ids, []string{"urgent"}, []string{"later"})
+	for _, id := range ids {
+		task := m.Tasks[m.TaskIndexByID(id)]
+		if task.Priority != PriorityHigh || task.Category != "Errands" || slices.Contains(task.Tags(), "later") || !slices.Contains(task.Tags(), "urgent") {
+			t.Errorf("Bulk edit missed %s: %+v", id, task)
+		}
+	}
+	if tags := m.Tasks[m.TaskIndexByID("dddddd")].Tags(); !slices.Equal(tags, []string{"home", "urgent"}) {
+		t.Errorf("Tags = %v, want [home urgent]", tags)
+	}
+
+	if n := m.SetTasksDone(ids, true); n != 3 {
+		t.Errorf("SetTasksDone completed %d tasks, want 3", n)
+	}
+	before := m.Checkpoint()
+	if n := m.DeleteTasks(ids); n != 3 || len(m.Tasks) != 1 || len(m.MarkedIDs()) != 0 {
+		t.Fatalf("DeleteTasks deleted %d, left %d tasks and %v marked", n, len(m.Tasks), m.MarkedIDs())
+	}
+	m.SetStatus("3 task(s) deleted")
+	m.Record(before)
+	if _, ok := m.Undo(); !ok || len(m.Tasks) != 4 {
+		t.Errorf("One undo should bring back every deleted task, have %d", len(m.Tasks))
+	}
+}
+
+func TestBulkCompleteChecks(t *testing.T) {
+	m := NewModel([]Task{
+		{ID: "spec01", Description: "Write spec"},
+		{ID: "build1", Description: "Build it", Metadata: map[string]string{"blocked-by": "spec01"}},
+		{ID: "test01", Description: "Test it", Metadata: map[string]string{"blocked-by": "build1,spec01"}},
+		{ID: "part01", Description: "Part one", ParentID: "build1"},
+		{ID: "part02", Description: "Part two", ParentID: "part01"},
+		{ID: "part03", Description: "Part three", ParentID: "build1", Done: true},
+	})
+
+	blockers := m.BlockersOf([]string{"build1", "test01"})
+	if len(blockers) != 1 || blockers[0].ID != "spec01" {
+		t.Errorf("BlockersOf = %+v, want only spec01", blockers)
+	}
+	if blockers := m.BlockersOf([]string{"spec01", "build1", "test01"}); len(blockers) != 0 {
+		t.Errorf("Blockers completed together should be left out, got %+v", blockers)
+	}
+	if n := m.PendingSubtasksOf([]string{"build1", "part01"}); n != 2 {
+		t.Errorf("PendingSubtasksOf = %d, want 2", n)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	today := time.Now().Format(DateLayout)
+	m := NewModel([]Task{
+		{ID: "login1", Description: "Fix login page", Category: "Web", Priority: PriorityHigh, Metadata: map[string]string{"tags": "backend", "due": today}},
+		{ID: "docs01", Description: "Write docs", Category: "Web", Priority: PriorityLow, Notes: "Mention the login page", Metadata: map[string]string{"tags": "docs"}},
+		{ID: "ship01", Description: "Ship it", Category: "Ops", Priority: PriorityCritical, Metadata: map[string]string{"tags": "backend", "blocked-by": "login1"}},
+		{ID: "done01", Description: "Old chore", Category: "Ops", Done: true, Metadata: map[string]string{"due": "2020-01-01"}},
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`priority>=high tag:backend due<7d -status:blocked "login page"`, []string{"login1"}},
+		{`"login page"`, []string{"login1", "docs01"}},
+		{`status:blocked OR category:ops AND status:done`, []string{"ship01", "done01"}},
+		{`NOT (tag:backend OR has:due)`, []string{"docs01"}},
+		{`priority<medium`, []string{"docs01", "done01"}},
+		{`due:none`, []string{"docs01", "ship01"}},
+		{`due<-1y !status:pending`, []string{"done01"}},
+		{`id!=login1 cat:"web"`, []string{"docs01"}},
+	}
+	for _, test := range tests {
+		q, err := ParseQuery(test.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", test.query, err)
+			continue
+		}
+		var got []string
+		for _, task := range m.QueryTasks(q) {
+			got = append(got, task.ID)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s matched %v, want %v", test.query, got, test.want)
+		}
+	}
+
+	for query, offset := range map[string]int{
+		`priority>=urgent`:  10,
+		`(tag:a OR tag:b`:   0,
+		`tag:a OR`:          8,
+		`due<sometime`:      4,
+		`"unfinished quote`: 0,
+		`status<done`:       7,
+	} {
+		var queryErr *QueryError
+		if _, err := ParseQuery(query); !errors.As(err, &queryErr) || queryErr.Offset != offset {
+			t.Errorf("ParseQuery(%q) = %v, want an error at %d", query, err, offset)
+		}
+	}
+
+	if err := m.SetQuery("tag:backend"); err != nil || len(m.GetFilteredTasks()) != 2 {
+		t.Errorf("SetQuery showed %d tasks: %v", len(m.GetFilteredTasks()), err)
+	}
+	if err := m.SetQuery("tag:"); err == nil || m.Query.Text != "tag:backend" {
+		t.Error("A bad query replaced the one in use")
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	if positions, ok := FuzzyMatch("lgn", "Fix Login page"); !ok || !slices.Equal(positions, []int{4, 6, 8}) {
+		t.Errorf("FuzzyMatch(lgn) = %v, %v", positions, ok)
+	}
+	if positions, ok := FuzzyMatch("page", "a page, then a pager"); !ok || !slices.Equal(positions, []int{2, 3, 4, 5}) {
+		t.Errorf("Text containing the pattern should match there, got %v", positions)
+	}
+	if positions, _ := FuzzyMatch("ab", "a...a.b"); !slices.Equal(positions, []int{4, 6}) {
+		t.Errorf("FuzzyMatch should pick the shortest stretch, got %v", positions)
+	}
+	if _, ok := FuzzyMatch("xyz", "Fix login"); ok {
+		t.Error("FuzzyMatch matched letters that aren't there")
+	}
+
+	m := newTestModel()
+	m.Tasks = append(m.Tasks,
+		Task{ID: "dddddd", Description: "Call plumber", Category: "Home", Notes: "Ask about the boiler"},
+		Task{ID: "eeeeee", Description: "Renew passport", Metadata: map[string]string{"tags": "errands"}},
+	)
+	m.Pagination.ItemsPerPage = 2
+	for search, want := range map[string][]string{
+		"plmbr":     {"dddddd"},
+		"boiler":    {"dddddd"},
+		"home call": {"dddddd"},
+		"errnd":     {"eeeeee"},
+		"wrk same":  {"bbbbbb", "cccccc"},
+	} {
+		m.SetSearch(search)
+		var got []string
+		for _, task := range m.GetFilteredTasks() {
+			got = append(got, task.ID)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("Search %q found %v, want %v", search, got, want)
+		}
+	}
+
+	// n and N move between matches across pages
+	m.SetSearch("e")
+	matches := len(m.GetFilteredTasks())
+	for range matches - 1 {
+		m.JumpToMatch(1)
+	}
+	if m.Pagination.Page != (matches-1)/2 || m.Cursor != (matches-1)%2 {
+		t.Errorf("After %d jumps on page %d at %d", matches-1, m.Pagination.Page, m.Cursor)
+	}
+	if m.JumpToMatch(1); m.Pagination.Page != 0 || m.Cursor != 0 {
+		t.Error("Jumping past the last match should wrap to the first")
+	}
+}
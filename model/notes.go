@@ -0,0 +1,140 @@
+package model
+
+import "strings"
+
+// NotesEditor holds the state of the multi-line editor for a task's notes.
+// The cursor column counts runes, so it stays on character boundaries.
+type NotesEditor struct {
+	TaskID string
+	Lines  []string
+	Row    int
+	Col    int
+}
+
+// OpenNotesEditor starts editing the notes of the task with the given ID,
+// with the cursor at the end. It returns false if there is no such task.
+func (m *Model) OpenNotesEditor(id string) bool {
+	idx := m.TaskIndexByID(id)
+	if idx < 0 {
+		return false
+	}
+
+	lines := strings.Split(m.Tasks[idx].Notes, "\n")
+	m.NotesEditor = &NotesEditor{
+		TaskID: id,
+		Lines:  lines,
+		Row:    len(lines) - 1,
+		Col:    len([]rune(lines[len(lines)-1])),
+	}
+	return true
+}
+
+// SaveNotes stores the edited notes on the task and closes the editor.
+// Trailing blank lines are dropped. It returns false if the task is gone.
+func (m *Model) SaveNotes() bool {
+	editor := m.NotesEditor
+	m.NotesEditor = nil
+	if editor == nil {
+		return false
+	}
+
+	idx := m.TaskIndexByID(editor.TaskID)
+	if idx < 0 {
+		return false
+	}
+
+	lines := make([]string, len(editor.Lines))
+	for i, line := range editor.Lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	m.Tasks[idx].Notes = strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	return true
+}
+
+// Insert types text at the cursor. Newlines in pasted text start new lines.
+func (e *NotesEditor) Insert(text string) {
+	for i, part := range strings.Split(text, "\n") {
+		if i > 0 {
+			e.Newline()
+		}
+		line := []rune(e.Lines[e.Row])
+		inserted := []rune(strings.ReplaceAll(part, "\r", ""))
+		line = append(line[:e.Col], append(inserted, line[e.Col:]...)...)
+		e.Lines[e.Row] = string(line)
+		e.Col += len(inserted)
+	}
+}
+
+// Newline splits the current line at the cursor
+func (e *NotesEditor) Newline() {
+	line := []rune(e.Lines[e.Row])
+	before, after := string(line[:e.Col]), string(line[e.Col:])
+
+	e.Lines[e.Row] = before
+	e.Lines = append(e.Lines[:e.Row+1], append([]string{after}, e.Lines[e.Row+1:]...)...)
+	e.Row++
+	e.Col = 0
+}
+
+// Backspace deletes the character before the cursor, joining the line with
+// the one above at the start of a line
+func (e *NotesEditor) Backspace() {
+	if e.Col > 0 {
+		line := []rune(e.Lines[e.Row])
+		e.Lines[e.Row] = string(append(line[:e.Col-1], line[e.Col:]...))
+		e.Col--
+		return
+	}
+	if e.Row == 0 {
+		return
+	}
+
+	above := e.Lines[e.Row-1]
+	e.Lines[e.Row-1] = above + e.Lines[e.Row]
+	e.Lines = append(e.Lines[:e.Row], e.Lines[e.Row+1:]...)
+	e.Row--
+	e.Col = len([]rune(above))
+}
+
+// Delete deletes the character under the cursor, joining the next line at
+// the end of a line
+func (e *NotesEditor) Delete() {
+	line := []rune(e.Lines[e.Row])
+	if e.Col < len(line) {
+		e.Lines[e.Row] = string(append(line[:e.Col], line[e.Col+1:]...))
+		return
+	}
+	if e.Row == len(e.Lines)-1 {
+		return
+	}
+
+	e.Lines[e.Row] += e.Lines[e.Row+1]
+	e.Lines = append(e.Lines[:e.Row+1], e.Lines[e.Row+2:]...)
+}
+
+// Move moves the cursor by rows and columns, wrapping to the neighbouring
+// line at either end of a line
+func (e *NotesEditor) Move(rows, cols int) {
+	e.Row = min(max(e.Row+rows, 0), len(e.Lines)-1)
+
+	e.Col += cols
+	switch {
+	case e.Col < 0 && e.Row > 0:
+		e.Row--
+		e.Col = len([]rune(e.Lines[e.Row]))
+	case e.Col > len([]rune(e.Lines[e.Row])) && cols > 0 && e.Row < len(e.Lines)-1:
+		e.Row++
+		e.Col = 0
+	}
+	e.Col = min(max(e.Col, 0), len([]rune(e.Lines[e.Row])))
+}
+
+// Home moves the cursor to the start of the line
+func (e *NotesEditor) Home() {
+	e.Col = 0
+}
+
+// End moves the cursor to the end of the line
+func (e *NotesEditor) End() {
+	e.Col = len([]rune(e.Lines[e.Row]))
+}
@@ -0,0 +1,506 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Query is a parsed filter expression such as
+//
+//	priority>=high tag:backend due<7d -status:blocked "login page"
+//
+// Terms next to each other must all match; OR, NOT (or a leading - or !)
+// and parentheses combine them otherwise. A term is a field compared with
+// a value, or text to find in the description and notes.
+type Query struct {
+	Text  string // The expression as typed
+	match queryNode
+}
+
+// QueryError is a query that can't be parsed, and where the problem is
+type QueryError struct {
+	Offset  int // Byte offset of the problem in the query text
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s (column %d)", e.Message, e.Offset+1)
+}
+
+// queryContext holds what terms need to know beyond the task itself
+type queryContext struct {
+	now     time.Time
+	today   time.Time
+	blocked map[string]bool
+	waiting map[string]bool
+}
+
+// queryNode reports whether a task matches part of a query
+type queryNode func(task Task, ctx *queryContext) bool
+
+// Comparison operators, longest first so <= is found before <
+var queryOperators = []string{"!=", "<=", ">=", ":", "=", "<", ">"}
+
+// priorityRanks orders priorities for comparisons such as priority>=high
+var priorityRanks = map[Priority]int{
+	PriorityNone:     0,
+	PriorityLow:      1,
+	PriorityMedium:   2,
+	PriorityHigh:     3,
+	PriorityCritical: 4,
+}
+
+// ParseQuery parses a filter expression. A blank expression matches every
+// task and gives a nil Query. Errors are *QueryError.
+func ParseQuery(text string) (*Query, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	tokens, err := lexQuery(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	match, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEnd {
+		return nil, &QueryError{tok.pos, fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	return &Query{Text: text, match: match}, nil
+}
+
+// Match reports whether a task matches the query, given the tasks blocked
+// by others and those waiting to start. A nil Query matches every task.
+func (q *Query) Match(task Task, now time.Time, blocked, waiting map[string]bool) bool {
+	if q == nil {
+		return true
+	}
+	ctx := &queryContext{
+		now:     now,
+		today:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local),
+		blocked: blocked,
+		waiting: waiting,
+	}
+	return q.match(task, ctx)
+}
+
+// QueryTasks returns the tasks matching the query in list order, from every
+// tab, e.g. for listing them from the command line
+func (m Model) QueryTasks(q *Query) []Task {
+	now := time.Now()
+	blocked, waiting := m.blockedIDs(), m.waitingIDs(now)
+	var tasks []Task
+	for _, task := range m.Tasks {
+		if q.Match(task, now, blocked, waiting) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// SetQuery parses a filter expression and shows only the tasks matching it
+// from then on. A blank expression removes the filter. If the expression
+// can't be parsed the filter is left as it was.
+func (m *Model) SetQuery(text string) error {
+	q, err := ParseQuery(text)
+	if err != nil {
+		return err
+	}
+	m.Query = q
+	m.Cursor = 0
+	m.recalculatePagination()
+	return nil
+}
+
+// Kinds of query tokens
+const (
+	tokenWord   = iota // A term or keyword; quoted parts are unquoted
+	tokenPhrase        // Quoted text
+	tokenOpen          // (
+	tokenClose         // )
+	tokenEnd           // The end of the query
+)
+
+// queryToken is a word, phrase or parenthesis in a query
+type queryToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+// lexQuery splits a query into tokens. Quotes may also appear inside a
+// word, as in category:"side project".
+func lexQuery(text string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, queryToken{tokenOpen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{tokenClose, ")", i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(text[i+1:], '"')
+			if end < 0 {
+				return nil, &QueryError{i, "missing closing quote"}
+			}
+			tokens = append(tokens, queryToken{tokenPhrase, text[i+1 : i+1+end], i})
+			i += end + 2
+		default:
+			start := i
+			var word strings.Builder
+			for i < len(text) && !strings.ContainsRune(" \t()", rune(text[i])) {
+				if text[i] != '"' {
+					word.WriteByte(text[i])
+					i++
+					continue
+				}
+				end := strings.IndexByte(text[i+1:], '"')
+				if end < 0 {
+					return nil, &QueryError{i, "missing closing quote"}
+				}
+				word.WriteString(text[i+1 : i+1+end])
+				i += end + 2
+			}
+			tokens = append(tokens, queryToken{tokenWord, word.String(), start})
+		}
+	}
+	return append(tokens, queryToken{tokenEnd, "", len(text)}), nil
+}
+
+// queryParser parses tokens by recursive descent. OR binds more loosely
+// than AND, which binds more loosely than NOT.
+type queryParser struct {
+	tokens []queryToken
+	next   int
+}
+
+// peek returns the next token without consuming it
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.next]
+}
+
+// take consumes and returns the next token
+func (p *queryParser) take() queryToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEnd {
+		p.next++
+	}
+	return tok
+}
+
+// isKeyword reports whether a token is the keyword AND, OR or NOT, in any case
+func (tok queryToken) isKeyword(keyword string) bool {
+	return tok.kind == tokenWord && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("or") {
+		p.take()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNodes(left, right)
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind == tokenEnd || tok.kind == tokenClose || tok.isKeyword("or") {
+			return left, nil
+		}
+		if tok.isKeyword("and") {
+			p.take()
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNodes(left, right)
+	}
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	tok := p.take()
+	switch {
+	case tok.isKeyword("not") || (tok.kind == tokenWord && (tok.text == "-" || tok.text == "!")):
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode(node), nil
+	case tok.kind == tokenOpen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.take().kind != tokenClose {
+			return nil, &QueryError{tok.pos, "missing closing parenthesis"}
+		}
+		return node, nil
+	case tok.kind == tokenClose:
+		return nil, &QueryError{tok.pos, "unexpected )"}
+	case tok.kind == tokenEnd:
+		return nil, &QueryError{tok.pos, "expected a term"}
+	case tok.kind == tokenPhrase:
+		return textNode(tok.text), nil
+	case tok.isKeyword("and") || tok.isKeyword("or"):
+		return nil, &QueryError{tok.pos, fmt.Sprintf("expected a term before %s", tok.text)}
+	}
+
+	// A leading - or ! negates the term, as in -status:blocked
+	if tok.text[0] == '-' || tok.text[0] == '!' {
+		node, err := parseTerm(tok.text[1:], tok.pos+1)
+		if err != nil {
+			return nil, err
+		}
+		return notNode(node), nil
+	}
+	return parseTerm(tok.text, tok.pos)
+}
+
+func andNodes(a, b queryNode) queryNode {
+	return func(task Task, ctx *queryContext) bool { return a(task, ctx) && b(task, ctx) }
+}
+
+func orNodes(a, b queryNode) queryNode {
+	return func(task Task, ctx *queryContext) bool { return a(task, ctx) || b(task, ctx) }
+}
+
+func notNode(node queryNode) queryNode {
+	return func(task Task, ctx *queryContext) bool { return !node(task, ctx) }
+}
+
+// textNode matches tasks whose description or notes contain the text,
+// ignoring case
+func textNode(text string) queryNode {
+	text = strings.ToLower(text)
+	return func(task Task, ctx *queryContext) bool {
+		return strings.Contains(strings.ToLower(task.Description), text) ||
+			strings.Contains(strings.ToLower(task.Notes), text)
+	}
+}
+
+// parseTerm parses a field comparison such as due<7d, or text to search
+// for if the word has no operator. pos is where the word starts.
+func parseTerm(word string, pos int) (queryNode, error) {
+	at, op := -1, ""
+	for _, candidate := range queryOperators {
+		if i := strings.Index(word, candidate); i > 0 && (at < 0 || i < at) {
+			at, op = i, candidate
+		}
+	}
+	if at < 0 {
+		return textNode(word), nil
+	}
+
+	field, value := strings.ToLower(word[:at]), word[at+len(op):]
+	if value == "" {
+		return nil, &QueryError{pos + at, fmt.Sprintf("missing value after %s%s", field, op)}
+	}
+	valuePos := pos + at + len(op)
+	fail := func(format string, args ...any) (queryNode, error) {
+		return nil, &QueryError{valuePos, fmt.Sprintf(format, args...)}
+	}
+	equality := op == ":" || op == "=" || op == "!="
+
+	var node queryNode
+	switch field {
+	case "priority", "p":
+		want, ok := queryPriority(value)
+		if !ok {
+			return fail("unknown priority %q", value)
+		}
+		node = func(task Task, ctx *queryContext) bool {
+			return compare(op, priorityRanks[task.Priority]-priorityRanks[want])
+		}
+		return node, nil
+	case "status", "is":
+		if !equality {
+			return fail("status can only be compared with : or !=")
+		}
+		node, ok := statusNode(strings.ToLower(value))
+		if !ok {
+			return fail("unknown status %q (done, pending, blocked, waiting, archived, overdue)", value)
+		}
+		return negateIf(op, node), nil
+	case "tag", "tags":
+		if !equality {
+			return fail("tags can only be compared with : or !=")
+		}
+		tag := strings.TrimPrefix(value, "#")
+		node = func(task Task, ctx *queryContext) bool {
+			return slices.ContainsFunc(task.Tags(), func(t string) bool { return strings.EqualFold(t, tag) })
+		}
+		return negateIf(op, node), nil
+	case "category", "cat":
+		if !equality {
+			return fail("categories can only be compared with : or !=")
+		}
+		node = func(task Task, ctx *queryContext) bool { return strings.EqualFold(task.Category, value) }
+		return negateIf(op, node), nil
+	case "id":
+		if !equality {
+			return fail("IDs can only be compared with : or !=")
+		}
+		node = func(task Task, ctx *queryContext) bool { return task.ID == value }
+		return negateIf(op, node), nil
+	case "text", "desc", "description":
+		if !equality {
+			return fail("text can only be searched with : or !=")
+		}
+		return negateIf(op, textNode(value)), nil
+	case "has":
+		if !equality {
+			return fail("has can only be used with : or !=")
+		}
+		key := strings.ToLower(value)
+		node = func(task Task, ctx *queryContext) bool {
+			if key == "notes" {
+				return task.Notes != ""
+			}
+			return taskDateValue(task, key) != "" || task.Metadata[key] != ""
+		}
+		return negateIf(op, node), nil
+	case "due", "start", "wait", "created", "completed":
+		return dateNode(field, op, value, valuePos)
+	}
+
+	// Any other field is a metadata key
+	if !equality {
+		return fail("@%s can only be compared with : or !=", field)
+	}
+	node = func(task Task, ctx *queryContext) bool { return strings.EqualFold(task.Metadata[field], value) }
+	return negateIf(op, node), nil
+}
+
+// negateIf inverts a node for the != operator
+func negateIf(op string, node queryNode) queryNode {
+	if op == "!=" {
+		return notNode(node)
+	}
+	return node
+}
+
+// compare applies an operator to the difference between two values
+func compare(op string, diff int) bool {
+	switch op {
+	case "<":
+		return diff < 0
+	case "<=":
+		return diff <= 0
+	case ">":
+		return diff > 0
+	case ">=":
+		return diff >= 0
+	case "!=":
+		return diff != 0
+	}
+	return diff == 0
+}
+
+// queryPriority reads a priority named in a query, including none
+func queryPriority(value string) (Priority, bool) {
+	priority := Priority(strings.ToLower(value))
+	if priority == "none" {
+		priority = PriorityNone
+	}
+	_, ok := priorityRanks[priority]
+	return priority, ok
+}
+
+// statusNode returns the node matching tasks with a status
+func statusNode(status string) (queryNode, bool) {
+	switch status {
+	case "done", "completed", "complete":
+		return func(task Task, ctx *queryContext) bool { return task.Done }, true
+	case "pending", "open", "todo":
+		return func(task Task, ctx *queryContext) bool { return !task.Done }, true
+	case "blocked":
+		return func(task Task, ctx *queryContext) bool { return ctx.blocked[task.ID] }, true
+	case "waiting", "snoozed":
+		return func(task Task, ctx *queryContext) bool { return !task.Done && ctx.waiting[task.ID] }, true
+	case "archived":
+		return func(task Task, ctx *queryContext) bool { return task.Archived }, true
+	case "overdue":
+		return func(task Task, ctx *queryContext) bool { return task.DueStatus(ctx.now) == DueOverdue }, true
+	}
+	return nil, false
+}
+
+// dateNode compares one of the task's dates, by day, with a date in any
+// form @due accepts, a count of days, weeks, months or years from today
+// such as 7d or -2w, or none
+func dateNode(field, op, value string, pos int) (queryNode, error) {
+	value = strings.ToLower(value)
+	if value == "none" {
+		if op != ":" && op != "=" && op != "!=" {
+			return nil, &QueryError{pos, fmt.Sprintf("%s:none can only be compared with : or !=", field)}
+		}
+		node := func(task Task, ctx *queryContext) bool { return taskDateValue(task, field) == "" }
+		return negateIf(op, node), nil
+	}
+
+	// Resolve the value now to catch mistakes, and again when matching so a
+	// query kept open past midnight stays relative to the current day
+	resolve := func(today time.Time) (time.Time, bool) {
+		if day, ok := addPeriod(today, value); ok {
+			return day, true
+		}
+		return resolveDay(value, today)
+	}
+	if _, ok := resolve(time.Now()); !ok {
+		return nil, &QueryError{pos, fmt.Sprintf("can't read date %q", value)}
+	}
+
+	return func(task Task, ctx *queryContext) bool {
+		date, _, ok := parseDate(taskDateValue(task, field))
+		if !ok {
+			return false
+		}
+		want, _ := resolve(ctx.today)
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+		return compare(op, day.Compare(want))
+	}, nil
+}
+
+// taskDateValue returns one of the task's dates in DateTimeLayout, or as
+// written for metadata dates. It is empty if the task has no such date.
+func taskDateValue(task Task, field string) string {
+	var at time.Time
+	switch field {
+	case "created":
+		at = task.CreatedAt
+	case "completed":
+		at = task.CompletedAt
+	case "due":
+		return task.Metadata["due"]
+	case "start", "wait":
+		return task.Metadata[task.startKey()]
+	default:
+		return ""
+	}
+	if at.IsZero() {
+		return ""
+	}
+	return at.Local().Format(DateTimeLayout)
+}
@@ -0,0 +1,195 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence is a parsed @every rule. A rule repeats after a number of days
+// or months, or on a set of weekdays.
+type Recurrence struct {
+	Days     int
+	Months   int
+	Weekdays []time.Weekday
+}
+
+// weekdayNames maps the names accepted in @every rules to weekdays
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseRecurrence parses an @every rule: daily, weekly, monthly, yearly,
+// weekdays, a count of days, weeks, months or years such as 3d, 2w, 6m or
+// 1y, or a comma-separated list of weekdays such as mon,thu.
+func ParseRecurrence(rule string) (Recurrence, error) {
+	rule = strings.ToLower(strings.TrimSpace(rule))
+	if rule == "" {
+		return Recurrence{}, fmt.Errorf("empty @every rule")
+	}
+
+	switch rule {
+	case "daily", "day":
+		return Recurrence{Days: 1}, nil
+	case "weekly", "week":
+		return Recurrence{Days: 7}, nil
+	case "monthly", "month":
+		return Recurrence{Months: 1}, nil
+	case "yearly", "year", "annually":
+		return Recurrence{Months: 12}, nil
+	case "weekdays", "weekday":
+		return Recurrence{Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}}, nil
+	}
+
+	if len(rule) > 1 {
+		if n, err := strconv.Atoi(rule[:len(rule)-1]); err == nil && n > 0 {
+			switch rule[len(rule)-1] {
+			case 'd':
+				return Recurrence{Days: n}, nil
+			case 'w':
+				return Recurrence{Days: 7 * n}, nil
+			case 'm':
+				return Recurrence{Months: n}, nil
+			case 'y':
+				return Recurrence{Months: 12 * n}, nil
+			}
+		}
+	}
+
+	var r Recurrence
+	for _, name := range strings.Split(rule, ",") {
+		day, ok := weekdayNames[name]
+		if !ok {
+			return Recurrence{}, fmt.Errorf("unknown @every rule %q", rule)
+		}
+		r.Weekdays = append(r.Weekdays, day)
+	}
+	return r, nil
+}
+
+// Next returns the first occurrence after the one due on due that falls
+// after the day of now, so a chore completed late isn't already overdue.
+// The time of day of due is kept.
+func (r Recurrence) Next(due, now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, due.Location())
+
+	if len(r.Weekdays) > 0 {
+		next := due.AddDate(0, 0, 1)
+		for next.Before(today.AddDate(0, 0, 1)) || !r.onWeekday(next.Weekday()) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	}
+
+	for step := 1; ; step++ {
+		next := addMonths(due, r.Months*step).AddDate(0, 0, r.Days*step)
+		if !next.Before(today.AddDate(0, 0, 1)) {
+			return next
+		}
+	}
+}
+
+// String describes the rule, e.g. "every 2 weeks" or "on Mon, Thu"
+func (r Recurrence) String() string {
+	if len(r.Weekdays) > 0 {
+		names := make([]string, len(r.Weekdays))
+		for i, day := range r.Weekdays {
+			names[i] = day.String()[:3]
+		}
+		return "on " + strings.Join(names, ", ")
+	}
+
+	n, unit := r.Days, "day"
+	switch {
+	case r.Months > 0 && r.Months%12 == 0:
+		n, unit = r.Months/12, "year"
+	case r.Months > 0:
+		n, unit = r.Months, "month"
+	case r.Days%7 == 0:
+		n, unit = r.Days/7, "week"
+	}
+	if n == 1 {
+		return "every " + unit
+	}
+	return fmt.Sprintf("every %d %ss", n, unit)
+}
+
+// onWeekday reports whether the rule falls on the given weekday
+func (r Recurrence) onWeekday(day time.Weekday) bool {
+	for _, weekday := range r.Weekdays {
+		if weekday == day {
+			return true
+		}
+	}
+	return false
+}
+
+// addMonths adds months to t, moving to the last day of the month when the
+// day doesn't exist there, e.g. from January 31 to February 28
+func addMonths(t time.Time, months int) time.Time {
+	if months == 0 {
+		return t
+	}
+	firstOfMonth := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	return firstOfMonth.AddDate(0, 0, min(t.Day(), lastDay)-1)
+}
+
+// Recurrence returns the task's @every rule. ok is false if it has none or
+// the rule can't be parsed.
+func (t Task) Recurrence() (r Recurrence, ok bool) {
+	rule := t.Metadata["every"]
+	if rule == "" {
+		return Recurrence{}, false
+	}
+	r, err := ParseRecurrence(rule)
+	return r, err == nil
+}
+
+// completeRecurring completes the task at idx and stops its timer if one is
+// running. If the task has an @every rule, the rule moves to a new pending
+// copy due on the next occurrence. The copy is added after the task and its
+// ID is returned. Reopening the completed task later doesn't repeat it again.
+func (m *Model) completeRecurring(idx int, now time.Time) string {
+	m.Tasks[idx].stopTimer(now)
+	m.Tasks[idx].SetDone(true)
+
+	r, ok := m.Tasks[idx].Recurrence()
+	if !ok {
+		return ""
+	}
+
+	// Without a due date the task repeats from when it was completed
+	due, layout, hasDue := m.Tasks[idx].Due()
+	if !hasDue {
+		due = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		layout = DateLayout
+	}
+
+	next := m.Tasks[idx].Clone()
+	next.ID = m.uniqueTaskID()
+	next.Done = false
+	next.CompletedAt = time.Time{}
+	next.CreatedAt = now
+	next.DeleteMetadata("spent")
+	nextDue := r.Next(due, now)
+	next.SetMetadata("due", nextDue.Format(layout))
+
+	// A start date keeps its distance from the due date
+	if start, startLayout, ok := next.Start(); ok {
+		days := int(math.Round(nextDue.Sub(due).Hours() / 24))
+		next.SetMetadata(next.startKey(), start.AddDate(0, 0, days).Format(startLayout))
+	}
+
+	m.Tasks[idx].DeleteMetadata("every")
+	m.Tasks = append(m.Tasks[:idx+1], append([]Task{next}, m.Tasks[idx+1:]...)...)
+	return next.ID
+}
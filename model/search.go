@@ -0,0 +1,119 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FuzzyMatch reports whether the letters of pattern appear in text in order,
+// ignoring case, and returns the positions (in runes) of the text matching
+// them. Text containing the pattern as is matches there; otherwise the
+// shortest stretch of text holding the letters is used.
+func FuzzyMatch(pattern, text string) (positions []int, ok bool) {
+	p, t := lowerRunes(pattern), lowerRunes(text)
+	if len(p) == 0 {
+		return nil, true
+	}
+
+	if i := strings.Index(string(t), string(p)); i >= 0 {
+		start := len([]rune(string(t)[:i]))
+		for j := range p {
+			positions = append(positions, start+j)
+		}
+		return positions, true
+	}
+
+	for start := range t {
+		if t[start] != p[0] {
+			continue
+		}
+		candidate := []int{start}
+		for i := start + 1; i < len(t) && len(candidate) < len(p); i++ {
+			if t[i] == p[len(candidate)] {
+				candidate = append(candidate, i)
+			}
+		}
+		if len(candidate) < len(p) {
+			break // No later start can match either
+		}
+		if positions == nil || candidate[len(candidate)-1]-start < positions[len(positions)-1]-positions[0] {
+			positions = candidate
+		}
+	}
+	return positions, positions != nil
+}
+
+// lowerRunes returns the runes of s in lower case, one for each rune of s
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// MatchesSearch reports whether every word of the search fuzzily matches
+// the task's description, category, one of its tags or a line of its notes
+func (t Task) MatchesSearch(search string) bool {
+	for _, word := range strings.Fields(search) {
+		if !t.matchesSearchWord(word) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesSearchWord reports whether a word of a search matches the task
+func (t Task) matchesSearchWord(word string) bool {
+	fields := append([]string{t.Description, t.Category}, t.Tags()...)
+	fields = append(fields, strings.Split(t.Notes, "\n")...)
+	for _, field := range fields {
+		if _, ok := FuzzyMatch(word, field); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchHighlights returns the positions (in runes) of text matched by the
+// words of a search, for highlighting
+func SearchHighlights(search, text string) map[int]bool {
+	highlights := make(map[int]bool)
+	for _, word := range strings.Fields(search) {
+		positions, _ := FuzzyMatch(word, text)
+		for _, i := range positions {
+			highlights[i] = true
+		}
+	}
+	return highlights
+}
+
+// SetSearch narrows the list to the tasks matching a fuzzy search, starting
+// again from the first match. An empty search shows every task.
+func (m *Model) SetSearch(search string) {
+	m.Search = search
+	m.Cursor = 0
+	m.Pagination.Page = 0
+	m.recalculatePagination()
+}
+
+// JumpToMatch moves the cursor step tasks on through the list narrowed by
+// the search, turning pages and wrapping around at either end. It reports
+// false if nothing matches.
+func (m *Model) JumpToMatch(step int) bool {
+	matches := len(m.GetFilteredTasks())
+	if matches == 0 {
+		return false
+	}
+
+	perPage := m.Pagination.ItemsPerPage
+	if perPage <= 0 || matches <= perPage {
+		m.Cursor = ((m.Cursor+step)%matches + matches) % matches
+		return true
+	}
+	at := m.Pagination.Page*perPage + m.Cursor
+	at = ((at+step)%matches + matches) % matches
+	m.Pagination.Page = at / perPage
+	m.Cursor = at % perPage
+	return true
+}
@@ -0,0 +1,229 @@
+package model
+
+import "time"
+
+// IsMarked reports whether the task with the given ID is marked
+func (m Model) IsMarked(id string) bool {
+	_, ok := m.SelectedTasks[id]
+	return ok
+}
+
+// ToggleMark marks or unmarks the task with the given ID and makes it where
+// the next range selection starts. It returns whether the task is now marked.
+func (m *Model) ToggleMark(id string) bool {
+	if m.SelectedTasks == nil {
+		m.SelectedTasks = make(map[string]struct{})
+	}
+	m.SelectionAnchor = id
+	if m.IsMarked(id) {
+		delete(m.SelectedTasks, id)
+		return false
+	}
+	m.SelectedTasks[id] = struct{}{}
+	return true
+}
+
+// MarkRange marks every task shown between the last task marked and the
+// one with the given ID, both included. Without a last task only the given
+// one is marked. It returns how many tasks are marked.
+func (m *Model) MarkRange(id string) int {
+	if m.SelectedTasks == nil {
+		m.SelectedTasks = make(map[string]struct{})
+	}
+	shown := m.GetFilteredTasks()
+	from, to := -1, -1
+	for i, task := range shown {
+		if task.ID == m.SelectionAnchor {
+			from = i
+		}
+		if task.ID == id {
+			to = i
+		}
+	}
+	if to < 0 {
+		return len(m.SelectedTasks)
+	}
+	if from < 0 {
+		from = to
+	}
+	if from > to {
+		from, to = to, from
+	}
+	for _, task := range shown[from : to+1] {
+		m.SelectedTasks[task.ID] = struct{}{}
+	}
+	m.SelectionAnchor = id
+	return len(m.SelectedTasks)
+}
+
+// MarkAll marks every task shown with the current tab and filters, or
+// unmarks them all if they already are. It returns how many are marked.
+func (m *Model) MarkAll() int {
+	shown := m.GetFilteredTasks()
+	all := len(shown) > 0
+	for _, task := range shown {
+		all = all && m.IsMarked(task.ID)
+	}
+	if all {
+		m.ClearMarks()
+		return 0
+	}
+
+	if m.SelectedTasks == nil {
+		m.SelectedTasks = make(map[string]struct{})
+	}
+	for _, task := range shown {
+		m.SelectedTasks[task.ID] = struct{}{}
+	}
+	return len(m.SelectedTasks)
+}
+
+// ClearMarks unmarks every task
+func (m *Model) ClearMarks() {
+	m.SelectedTasks = make(map[string]struct{})
+	m.SelectionAnchor = ""
+}
+
+// MarkedIDs returns the IDs of the marked tasks in list order, leaving out
+// marks on tasks that no longer exist
+func (m Model) MarkedIDs() []string {
+	var ids []string
+	for _, task := range m.Tasks {
+		if m.IsMarked(task.ID) {
+			ids = append(ids, task.ID)
+		}
+	}
+	return ids
+}
+
+// SetTasksDone completes or reopens the tasks with the given IDs. Completed
+// @every tasks repeat as they do when toggled one at a time. It returns how
+// many tasks changed.
+func (m *Model) SetTasksDone(ids []string, done bool) int {
+	now := time.Now()
+	changed := 0
+	for _, id := range ids {
+		idx := m.TaskIndexByID(id)
+		if idx < 0 || m.Tasks[idx].Done == done {
+			continue
+		}
+		if done {
+			m.completeRecurring(idx, now)
+		} else {
+			m.Tasks[idx].SetDone(false)
+		}
+		changed++
+	}
+	m.recalculatePagination()
+	return changed
+}
+
+// BlockersOf returns the open tasks that the pending tasks with the given IDs
+// wait for, each once. Blockers among the IDs are left out, as they are
+// completed along with the rest.
+func (m Model) BlockersOf(ids []string) []Task {
+	given := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		given[id] = true
+	}
+	var blockers []Task
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if idx := m.TaskIndexByID(id); idx < 0 || m.Tasks[idx].Done {
+			continue
+		}
+		for _, blocker := range m.OpenBlockers(id) {
+			if !given[blocker.ID] && !seen[blocker.ID] {
+				seen[blocker.ID] = true
+				blockers = append(blockers, blocker)
+			}
+		}
+	}
+	return blockers
+}
+
+// PendingSubtasksOf returns how many subtasks below the tasks with the given
+// IDs are pending, counting subtasks shared by nested tasks once
+func (m Model) PendingSubtasksOf(ids []string) int {
+	seen := make(map[string]bool)
+	pending := 0
+	for _, id := range ids {
+		for _, childID := range m.descendantIDs(id) {
+			if !seen[childID] && !m.Tasks[m.TaskIndexByID(childID)].Done {
+				pending++
+			}
+			seen[childID] = true
+		}
+	}
+	return pending
+}
+
+// ArchiveTasks archives or unarchives the tasks with the given IDs and
+// returns how many changed
+func (m *Model) ArchiveTasks(ids []string, archived bool) int {
+	changed := 0
+	for _, id := range ids {
+		if idx := m.TaskIndexByID(id); idx >= 0 && m.Tasks[idx].Archived != archived {
+			m.Tasks[idx].Archived = archived
+			changed++
+		}
+	}
+	m.recalculatePagination()
+	return changed
+}
+
+// DeleteTasks deletes the tasks with the given IDs as DeleteTask does and
+// returns how many were deleted
+func (m *Model) DeleteTasks(ids []string) int {
+	deleted := 0
+	for _, id := range ids {
+		if m.DeleteTask(id) {
+			delete(m.SelectedTasks, id)
+			deleted++
+		}
+	}
+	m.recalculatePagination()
+	if visible := len(m.GetVisibleTasks()); m.Cursor >= visible {
+		m.Cursor = max(visible-1, 0)
+	}
+	return deleted
+}
+
+// SetPriority gives the tasks with the given IDs a priority
+func (m *Model) SetPriority(ids []string, priority Priority) {
+	for _, id := range ids {
+		if idx := m.TaskIndexByID(id); idx >= 0 {
+			m.Tasks[idx].Priority = priority
+		}
+	}
+	if m.CurrentSort != "" {
+		m.SortTasks(m.CurrentSort)
+	}
+}
+
+// SetCategory moves the tasks with the given IDs to a category, as editing
+// each of them would
+func (m *Model) SetCategory(ids []string, category string) {
+	for _, id := range ids {
+		if idx := m.TaskIndexByID(id); idx >= 0 {
+			m.setCategory(idx, category)
+		}
+	}
+	m.recalculatePagination()
+}
+
+// ChangeTags adds tags to and removes tags from the tasks with the given IDs
+func (m *Model) ChangeTags(ids []string, add, remove []string) {
+	for _, id := range ids {
+		idx := m.TaskIndexByID(id)
+		if idx < 0 {
+			continue
+		}
+		for _, tag := range add {
+			m.Tasks[idx].AddTag(tag)
+		}
+		for _, tag := range remove {
+			m.Tasks[idx].RemoveTag(tag)
+		}
+	}
+}
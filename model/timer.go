@@ -0,0 +1,129 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Time tracking is kept in metadata so it travels with the task file:
+// @timer holds when the running timer was started, @spent the time logged
+// by earlier sessions and @estimate how long the task should take.
+
+// ParseEffort reads a length of time such as 45m, 2h or 1h30m
+func ParseEffort(value string) (time.Duration, bool) {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// FormatEffort writes a length of time as hours and minutes, e.g. 1h30m,
+// leaving out seconds unless it is shorter than a minute
+func FormatEffort(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	return formatDuration(d.Truncate(time.Minute))
+}
+
+// formatDuration writes d to the second without the zero parts that
+// time.Duration.String includes, so 1h30m0s becomes 1h30m
+func formatDuration(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+
+	var text string
+	if h > 0 {
+		text += fmt.Sprintf("%dh", h)
+	}
+	if m > 0 {
+		text += fmt.Sprintf("%dm", m)
+	}
+	if s > 0 || text == "" {
+		text += fmt.Sprintf("%ds", s)
+	}
+	return text
+}
+
+// Estimate returns the task's @estimate. ok is false if it has none or it
+// can't be read.
+func (t Task) Estimate() (d time.Duration, ok bool) {
+	if t.Metadata["estimate"] == "" {
+		return 0, false
+	}
+	return ParseEffort(t.Metadata["estimate"])
+}
+
+// TimerStarted returns when the task's running timer was started. ok is
+// false if no timer is running on it.
+func (t Task) TimerStarted() (start time.Time, ok bool) {
+	start, err := time.Parse(time.RFC3339, t.Metadata["timer"])
+	return start, err == nil
+}
+
+// TimeSpent returns the time logged against the task, including the running
+// timer up to now
+func (t Task) TimeSpent(now time.Time) time.Duration {
+	spent, _ := ParseEffort(t.Metadata["spent"])
+	if start, ok := t.TimerStarted(); ok && now.After(start) {
+		spent += now.Sub(start)
+	}
+	return spent
+}
+
+// stopTimer adds the running timer's session to @spent and stops it. It
+// returns the length of the session.
+func (t *Task) stopTimer(now time.Time) time.Duration {
+	start, ok := t.TimerStarted()
+	if !ok {
+		return 0
+	}
+	spent := t.TimeSpent(now)
+	t.DeleteMetadata("timer")
+	t.SetMetadata("spent", formatDuration(spent))
+	return max(now.Sub(start), 0)
+}
+
+// RunningTimer returns the task whose timer is running. ok is false if
+// there is none.
+func (m Model) RunningTimer() (task Task, ok bool) {
+	for _, task := range m.Tasks {
+		if _, running := task.TimerStarted(); running {
+			return task, true
+		}
+	}
+	return Task{}, false
+}
+
+// StartTimer starts a timer on the task with the given ID, first stopping
+// any timer running on another task, since only one may run at a time. It
+// returns the ID of the task stopped, if any, or false if there is no task
+// with the given ID.
+func (m *Model) StartTimer(id string, now time.Time) (stopped string, ok bool) {
+	idx := m.TaskIndexByID(id)
+	if idx < 0 {
+		return "", false
+	}
+	for i := range m.Tasks {
+		if _, running := m.Tasks[i].TimerStarted(); running && i != idx {
+			m.Tasks[i].stopTimer(now)
+			stopped = m.Tasks[i].ID
+		}
+	}
+	if _, running := m.Tasks[idx].TimerStarted(); !running {
+		m.Tasks[idx].SetMetadata("timer", now.UTC().Truncate(time.Second).Format(time.RFC3339))
+	}
+	return stopped, true
+}
+
+// StopTimer stops the timer running on the task with the given ID and
+// returns the length of the session that ended
+func (m *Model) StopTimer(id string, now time.Time) time.Duration {
+	idx := m.TaskIndexByID(id)
+	if idx < 0 {
+		return 0
+	}
+	return m.Tasks[idx].stopTimer(now)
+}
@@ -0,0 +1,212 @@
+package model
+
+import "time"
+
+// Subtasks are tasks whose ParentID names another task. In TODO.md they are
+// checklist items indented below their parent.
+
+// Children returns the direct subtasks of the task with the given ID, in
+// list order
+func (m Model) Children(id string) []Task {
+	var children []Task
+	if id == "" {
+		return children
+	}
+	for _, task := range m.Tasks {
+		if task.ParentID == id {
+			children = append(children, task)
+		}
+	}
+	return children
+}
+
+// HasChildren reports whether the task with the given ID has subtasks
+func (m Model) HasChildren(id string) bool {
+	for _, task := range m.Tasks {
+		if id != "" && task.ParentID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SubtaskProgress returns how many of a task's direct subtasks are done and
+// how many there are
+func (m Model) SubtaskProgress(id string) (done, total int) {
+	for _, child := range m.Children(id) {
+		total++
+		if child.Done {
+			done++
+		}
+	}
+	return done, total
+}
+
+// TreeDepths maps the ID of each task in the current view to how deeply it
+// is nested below the other tasks in the view
+func (m Model) TreeDepths() map[string]int {
+	tasks := m.GetFilteredTasks()
+	depths := make(map[string]int, len(tasks))
+	// Parents come before their subtasks, so their depth is already known
+	for _, task := range tasks {
+		if parentDepth, ok := depths[task.ParentID]; ok && task.ParentID != "" {
+			depths[task.ID] = parentDepth + 1
+		} else {
+			depths[task.ID] = 0
+		}
+	}
+	return depths
+}
+
+// descendantIDs returns the IDs of every subtask below the task with the
+// given ID, parents before their children
+func (m Model) descendantIDs(id string) []string {
+	var ids []string
+	seen := map[string]bool{id: true}
+	queue := []string{id}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, child := range m.Children(parent) {
+			if !seen[child.ID] {
+				seen[child.ID] = true
+				ids = append(ids, child.ID)
+				queue = append(queue, child.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// Subtree returns the task with the given ID followed by every subtask below
+// it, parents before their children
+func (m Model) Subtree(id string) []Task {
+	idx := m.TaskIndexByID(id)
+	if idx < 0 {
+		return nil
+	}
+
+	tasks := []Task{m.Tasks[idx]}
+	for _, childID := range m.descendantIDs(id) {
+		tasks = append(tasks, m.Tasks[m.TaskIndexByID(childID)])
+	}
+	return tasks
+}
+
+// PendingSubtasks returns how many subtasks below the task with the given ID,
+// at any depth, are not done
+func (m Model) PendingSubtasks(id string) int {
+	pending := 0
+	for _, childID := range m.descendantIDs(id) {
+		if !m.Tasks[m.TaskIndexByID(childID)].Done {
+			pending++
+		}
+	}
+	return pending
+}
+
+// CompleteSubtasks marks every subtask below the task with the given ID as
+// done and returns how many changed
+func (m *Model) CompleteSubtasks(id string) int {
+	completed := 0
+	for _, childID := range m.descendantIDs(id) {
+		idx := m.TaskIndexByID(childID)
+		if !m.Tasks[idx].Done {
+			m.completeRecurring(idx, time.Now())
+			completed++
+		}
+	}
+	return completed
+}
+
+// IsCollapsed reports whether the subtasks of the task with the given ID are
+// hidden
+func (m Model) IsCollapsed(id string) bool {
+	return m.Collapsed[id]
+}
+
+// ToggleCollapsed hides or shows the subtasks of the task with the given ID.
+// It returns false if the task has no subtasks.
+func (m *Model) ToggleCollapsed(id string) bool {
+	if !m.HasChildren(id) {
+		return false
+	}
+
+	if m.Collapsed == nil {
+		m.Collapsed = make(map[string]bool)
+	}
+	if m.Collapsed[id] {
+		delete(m.Collapsed, id)
+	} else {
+		m.Collapsed[id] = true
+	}
+
+	m.recalculatePagination()
+	m.SelectTask(id)
+	return true
+}
+
+// AddSubtask adds a new task below the task with the given ID, in the same
+// category. It returns false if there is no such task.
+func (m *Model) AddSubtask(parentID, description string, priority Priority, fields []MetadataField) bool {
+	idx := m.TaskIndexByID(parentID)
+	if idx < 0 {
+		return false
+	}
+
+	task := m.newTask(description, m.Tasks[idx].Category, priority, fields)
+	task.ParentID = parentID
+	task.Source = m.Tasks[idx].Source
+	m.Tasks = append(m.Tasks, task)
+
+	// Show the new subtask even if its siblings were hidden
+	delete(m.Collapsed, parentID)
+
+	m.SortTasks(SortByPriority)
+	return true
+}
+
+// treeOrder arranges tasks so each one is followed by its subtasks, keeping
+// the order of the list among siblings. Subtasks of collapsed tasks are left
+// out. Tasks whose parent isn't in the list are treated as top-level.
+func treeOrder(tasks []Task, collapsed map[string]bool) []Task {
+	present := make(map[string]bool, len(tasks))
+	children := make(map[string][]Task)
+	for _, task := range tasks {
+		present[task.ID] = true
+	}
+	for _, task := range tasks {
+		if task.ParentID != "" && present[task.ParentID] {
+			children[task.ParentID] = append(children[task.ParentID], task)
+		}
+	}
+
+	ordered := make([]Task, 0, len(tasks))
+	placed := make(map[string]bool, len(tasks))
+	var add func(task Task, hidden bool)
+	add = func(task Task, hidden bool) {
+		if placed[task.ID] {
+			return // A parent cycle
+		}
+		placed[task.ID] = true
+		if !hidden {
+			ordered = append(ordered, task)
+		}
+		for _, child := range children[task.ID] {
+			add(child, hidden || collapsed[task.ID])
+		}
+	}
+
+	for _, task := range tasks {
+		if task.ParentID == "" || !present[task.ParentID] {
+			add(task, false)
+		}
+	}
+	// Tasks in a parent cycle have no top-level ancestor; show them anyway
+	for _, task := range tasks {
+		if !placed[task.ID] {
+			add(task, false)
+		}
+	}
+	return ordered
+}
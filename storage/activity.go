@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+// activityLogName is the name of the activity log, which is kept in the
+// directory holding the backup directory
+const activityLogName = "activity.jsonl"
+
+// ActivityLogPath returns where changes to tasks are logged, one JSON event
+// per line. It is empty if no backup directory is configured.
+func ActivityLogPath() string {
+	if backupDirectory == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(filepath.Clean(backupDirectory)), activityLogName)
+}
+
+// logActivity appends events to the activity log, recording who made them
+// and the file they were saved to
+func logActivity(events []model.Event, file string) error {
+	path := ActivityLogPath()
+	if path == "" || len(events) == 0 {
+		return nil
+	}
+
+	actor := currentActor()
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		event.Actor = actor
+		event.File = file
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	// A single append keeps the lines of concurrent instances whole
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// currentActor names who is making changes: the user running tuiodo
+func currentActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "tuiodo"
+}
+
+// ActivityFilter selects events from the activity log. Empty fields match
+// every event.
+type ActivityFilter struct {
+	TaskID   string
+	Category string    // Matched without regard to case
+	Since    time.Time // Events at or after this time
+	Until    time.Time // Events before this time
+}
+
+// Match reports whether the event is selected by the filter
+func (f ActivityFilter) Match(event model.Event) bool {
+	return (f.TaskID == "" || event.TaskID == f.TaskID) &&
+		(f.Category == "" || strings.EqualFold(event.Category, f.Category)) &&
+		(f.Since.IsZero() || !event.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || event.Time.Before(f.Until))
+}
+
+// ReadActivity returns the events in the activity log selected by filter,
+// oldest first. Lines that can't be read are skipped. There are none if
+// nothing has been logged yet.
+func ReadActivity(filter ActivityFilter) ([]model.Event, error) {
+	path := ActivityLogPath()
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []model.Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event model.Event
+		if json.Unmarshal(scanner.Bytes(), &event) != nil {
+			continue
+		}
+		if filter.Match(event) {
+			events = append(events, event)
+		}
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// maxSymlinkHops bounds how many symlinks resolveTarget will follow
+const maxSymlinkHops = 40
+
+// resolveTarget follows path through any symlinks and returns the file that
+// should actually be written. Unlike filepath.EvalSymlinks it also works when
+// the final target doesn't exist yet (a dangling symlink or a new file).
+func resolveTarget(path string) (string, error) {
+	current := path
+
+	for i := 0; i < maxSymlinkHops; i++ {
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return current, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			return current, nil
+		}
+
+		link, err := os.Readlink(current)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(current), link)
+		}
+		current = link
+	}
+
+	return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+}
+
+// writeFileAtomic replaces the file at path with data so that readers only
+// ever see the old or the new content, never a partial write. The data is
+// written to a temporary file in the same directory, fsynced and renamed into
+// place. Symlinks are followed so the link itself survives, and the existing
+// file's permissions are kept.
+func writeFileAtomic(path string, data []byte) error {
+	target, err := resolveTarget(path)
+	if err != nil {
+		return err
+	}
+
+	// Keep the original permissions, defaulting to 0644 for new files
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(target); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	// Make sure the temporary file never outlives a failed write
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		return err
+	}
+	committed = true
+
+	// Flush the directory entry so the rename itself survives a crash
+	syncDir(dir)
+
+	return nil
+}
+
+// syncDir fsyncs a directory. Not every platform supports this, so it is
+// best-effort and errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+// ErrFileChanged is returned by Save when the stored tasks were modified by
+// something else since they were last loaded. The caller should reload (and
+// merge) before saving again so those changes aren't overwritten.
+var ErrFileChanged = errors.New("task file changed on disk")
+
+// Backend stores the task list
+type Backend interface {
+	// Path returns where the tasks are stored, for display
+	Path() string
+
+	// Load reads the tasks. A store that doesn't exist yet has no tasks.
+	Load() ([]model.Task, error)
+
+	// Loaded returns the tasks as of the last Load or Save without reading
+	// the store again
+	Loaded() []model.Task
+
+	// Save writes the tasks. It returns ErrFileChanged without writing if
+	// the store was modified by something else since the last Load or Save.
+	Save(tasks []model.Task) error
+
+	// Watch checks the store every interval and sends on the returned
+	// channel when it was modified by something else. Notifications are
+	// coalesced and repeat until the store is loaded again.
+	Watch(interval time.Duration) <-chan struct{}
+
+	// Backup writes a snapshot of the stored tasks into dir
+	Backup(dir string) error
+}
+
+// Backend names accepted by NewBackend
+const (
+	BackendMarkdown = "markdown"
+	BackendJSONL    = "jsonl"
+)
+
+// NewBackend creates a file backend by name. An empty name selects Markdown.
+// The JSON Lines backend swaps a .md extension for .jsonl, so the TODO.md
+// found by git detection becomes TODO.jsonl.
+func NewBackend(name, path string) (Backend, error) {
+	switch name {
+	case "", BackendMarkdown:
+		return NewMarkdownBackend(path), nil
+	case BackendJSONL:
+		if strings.EqualFold(filepath.Ext(path), ".md") {
+			path = strings.TrimSuffix(path, filepath.Ext(path)) + ".jsonl"
+		}
+		return NewJSONLBackend(path), nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q (expected %s or %s)", name, BackendMarkdown, BackendJSONL)
+}
@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+// backupTimeFormat is the timestamp in backup file names
+const backupTimeFormat = "20060102-150405"
+
+// backupFile is a backup found in the backup directory
+type backupFile struct {
+	name   string
+	source string // backupSource of the file backed up; empty in older names
+	time   time.Time
+	seq    int // Sequence number among backups taken in the same second
+}
+
+// backupSource identifies the file a backup was taken of by the first eight
+// hex digits of the SHA-1 of its absolute path. The project and global files
+// share the backup directory; the source keeps their backups apart.
+func backupSource(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha1.Sum([]byte(filepath.Clean(path)))
+	return hex.EncodeToString(sum[:4])
+}
+
+// ofSource reports whether the backup was taken of the file source names.
+// Backups named before the source was added could be of any file.
+func (f backupFile) ofSource(source string) bool {
+	return f.source == "" || f.source == source
+}
+
+// isBackupName reports whether name is a backup written by a file backend
+func isBackupName(name string) bool {
+	name = strings.TrimSuffix(name, ".gz")
+	return strings.HasPrefix(name, "TODO-") &&
+		(strings.HasSuffix(name, ".md") || strings.HasSuffix(name, ".jsonl"))
+}
+
+// backupFileName returns a name for a new backup of the file source names in
+// dir that doesn't clash with an existing one, adding -1, -2, ... for backups
+// in the same second. Compressed and uncompressed backups share the
+// numbering.
+func backupFileName(dir, source string, t time.Time, ext string) string {
+	base := "TODO-" + source + "-" + t.Format(backupTimeFormat)
+	name := base + ext
+	for n := 1; ; n++ {
+		if !backupExists(dir, name) {
+			return name
+		}
+		name = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+}
+
+// backupExists reports whether a backup with name, compressed or not, is in dir
+func backupExists(dir, name string) bool {
+	for _, candidate := range []string{name, name + ".gz"} {
+		if _, err := os.Lstat(filepath.Join(dir, candidate)); !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// listBackupFiles returns the backups of every file in the backup directory,
+// newest first
+func listBackupFiles() ([]backupFile, error) {
+	entries, err := os.ReadDir(backupDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []backupFile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if file, ok := parseBackupName(entry.Name()); ok {
+			files = append(files, file)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].time.Equal(files[j].time) {
+			return files[i].time.After(files[j].time)
+		}
+		return files[i].seq > files[j].seq
+	})
+	return files, nil
+}
+
+// parseBackupName extracts the source, timestamp and same-second sequence
+// number from a backup file name. Older names have no source.
+func parseBackupName(name string) (backupFile, bool) {
+	if !isBackupName(name) {
+		return backupFile{}, false
+	}
+
+	stem := strings.TrimPrefix(name, "TODO-")
+	stem = stem[:strings.Index(stem, ".")]
+
+	source := ""
+	if head, rest, ok := strings.Cut(stem, "-"); ok && len(head) == 8 && len(rest) >= len(backupTimeFormat) {
+		if _, err := hex.DecodeString(head); err == nil {
+			if _, err := time.Parse(backupTimeFormat, rest[:len(backupTimeFormat)]); err == nil {
+				source, stem = head, rest
+			}
+		}
+	}
+	if len(stem) < len(backupTimeFormat) {
+		return backupFile{}, false
+	}
+
+	t, err := time.ParseInLocation(backupTimeFormat, stem[:len(backupTimeFormat)], time.Local)
+	if err != nil {
+		return backupFile{}, false
+	}
+
+	seq := 0
+	if rest := stem[len(backupTimeFormat):]; rest != "" {
+		if seq, err = strconv.Atoi(strings.TrimPrefix(rest, "-")); err != nil || rest[0] != '-' {
+			return backupFile{}, false
+		}
+	}
+	return backupFile{name: name, source: source, time: t, seq: seq}, true
+}
+
+// readBackup parses a backup file in the format its extension names,
+// decompressing it first if it ends in .gz
+func readBackup(path string) ([]model.Task, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+		path = strings.TrimSuffix(path, ".gz")
+	}
+
+	if strings.HasSuffix(path, ".jsonl") {
+		return parseJSONLDocument(data).tasks(), nil
+	}
+	return parseDocument(data).tasks(), nil
+}
+
+// GetBackupDirectory returns the directory backups are written to
+func GetBackupDirectory() string {
+	return backupDirectory
+}
+
+// ListBackups returns the backups of the current task file, newest first
+func ListBackups() ([]model.Backup, error) {
+	if _, ok := backend.(*treeBackend); ok {
+		return nil, errTreeBackups
+	}
+	if backupDirectory == "" {
+		return nil, fmt.Errorf("no backup directory configured")
+	}
+
+	files, err := listBackupFiles()
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	source := backupSource(backend.Path())
+	backups := make([]model.Backup, 0, len(files))
+	for _, file := range files {
+		if !file.ofSource(source) {
+			continue
+		}
+		tasks, err := readBackup(filepath.Join(backupDirectory, file.name))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, model.Backup{Name: file.name, Time: file.time, Tasks: tasks})
+	}
+	return backups, nil
+}
+
+// LoadBackup reads the named backup of the current task file from the
+// backup directory
+func LoadBackup(name string) (model.Backup, error) {
+	file, ok := parseBackupName(name)
+	if !ok || filepath.Base(name) != name {
+		return model.Backup{}, fmt.Errorf("not a backup: %s", name)
+	}
+	if _, ok := backend.(*treeBackend); ok {
+		return model.Backup{}, errTreeBackups
+	}
+	if backupDirectory == "" {
+		return model.Backup{}, fmt.Errorf("no backup directory configured")
+	}
+	if !file.ofSource(backupSource(backend.Path())) {
+		return model.Backup{}, fmt.Errorf("%s is a backup of another task file", name)
+	}
+
+	tasks, err := readBackup(filepath.Join(backupDirectory, name))
+	if err != nil {
+		return model.Backup{}, err
+	}
+	return model.Backup{Name: name, Time: file.time, Tasks: tasks}, nil
+}
+
+// RestoreBackup replaces the stored tasks with those of the named backup and
+// returns them. The current tasks are backed up first so the restore can be
+// undone by restoring that backup. The changes are logged like a save.
+func RestoreBackup(name string) ([]model.Task, error) {
+	backup, err := LoadBackup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	storageWriteMu.Lock()
+	defer storageWriteMu.Unlock()
+
+	// Pick up the file as it is now, so the save below doesn't refuse to
+	// overwrite outside changes
+	before, err := backend.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := createBackup(); err != nil {
+		return nil, fmt.Errorf("failed to back up current tasks: %w", err)
+	}
+
+	if err := backend.Save(backup.Tasks); err != nil {
+		return nil, err
+	}
+	restored := backend.Loaded()
+	logActivity(model.TaskEvents(before, restored, time.Now()), backend.Path())
+	return restored, nil
+}
@@ -0,0 +1,835 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+// utf8BOM is the byte order mark some editors put at the start of a file
+const utf8BOM = "\xef\xbb\xbf"
+
+// footerComment is the attribution tag written at the bottom of new files.
+// It doesn't appear in the TUI but is visible in raw markdown files.
+const footerComment = "<!-- Optimized for [tuiodo](https://github.com/spmfte/tuiodo) -->"
+
+// lineKind identifies what a line of the TODO file holds
+type lineKind int
+
+const (
+	lineText    lineKind = iota // Anything tuiodo doesn't interpret
+	lineHeading                 // A "## Category" heading
+	lineTask                    // A "- [ ] task" checklist item
+	lineNote                    // Indented text below a task, part of its notes
+)
+
+// docLine is a single line of the TODO file
+type docLine struct {
+	kind     lineKind
+	text     string // Raw line content without the line ending
+	eol      string // Line ending as found in the file ("\n", "\r\n" or "")
+	category string // Heading name, or the category a task belongs to
+	indent   string // Leading whitespace of a task line
+	marker   string // List marker of a task line ("-", "*" or "+")
+	parent   string // ID of the task this line is indented below, if any
+
+	// noteIndent is the indentation of a task's notes, as found in the file
+	noteIndent string
+	task       model.Task // Task as parsed from the line
+	derivedID  bool       // Whether the task's ID was derived and isn't in the line yet
+}
+
+// document is a parsed TODO file that remembers everything needed to write
+// it back byte-for-byte: the BOM, line endings, category and task order, and
+// every line tuiodo doesn't understand.
+type document struct {
+	bom   bool
+	eol   string // Line ending used for lines tuiodo writes
+	isNew bool   // Whether the file didn't exist when it was loaded
+	lines []docLine
+
+	// indentUnit is added to a parent's indentation for the subtasks tuiodo
+	// writes, matching the file's first nested task
+	indentUnit string
+}
+
+// newDocument returns an empty document for a file that doesn't exist yet
+func newDocument() *document {
+	return &document{eol: "\n", isNew: true, indentUnit: "  "}
+}
+
+// parseDocument splits raw file content into a document
+func parseDocument(data []byte) *document {
+	doc := &document{eol: "\n", indentUnit: "  "}
+
+	if bytes.HasPrefix(data, []byte(utf8BOM)) {
+		doc.bom = true
+		data = data[len(utf8BOM):]
+	}
+
+	// Use the first line ending found for any lines we add later
+	if idx := bytes.IndexByte(data, '\n'); idx > 0 && data[idx-1] == '\r' {
+		doc.eol = "\r\n"
+	}
+
+	var currentCategory string
+	content := string(data)
+
+	for len(content) > 0 {
+		var text, eol string
+		if idx := strings.IndexByte(content, '\n'); idx >= 0 {
+			text, eol = content[:idx], "\n"
+			content = content[idx+1:]
+			if strings.HasSuffix(text, "\r") {
+				text, eol = text[:len(text)-1], "\r\n"
+			}
+		} else {
+			text, content = content, ""
+		}
+
+		line := docLine{kind: lineText, text: text, eol: eol}
+		trimmed := strings.TrimSpace(text)
+
+		if strings.HasPrefix(trimmed, "## ") {
+			// Category header
+			currentCategory = strings.TrimSpace(strings.TrimPrefix(trimmed, "## "))
+			line.kind = lineHeading
+			line.category = currentCategory
+		} else if indent, marker, done, rest, ok := splitTaskLine(text); ok {
+			line.kind = lineTask
+			line.category = currentCategory
+			line.indent = indent
+			line.marker = marker
+			line.task = parseTaskText(rest, done, currentCategory)
+		}
+
+		doc.lines = append(doc.lines, line)
+	}
+
+	doc.attachNotes()
+	doc.assignIDs()
+	doc.linkSubtasks()
+	return doc
+}
+
+// assignIDs gives every task without an @id an identifier derived from its
+// line. The ID stays the same across loads only as long as the line doesn't
+// change, so update writes it to the line the first time the file is saved
+// with changes.
+func (d *document) assignIDs() {
+	used := make(map[string]bool)
+	for i, line := range d.lines {
+		if line.kind != lineTask || line.task.ID == "" {
+			continue
+		}
+		if used[line.task.ID] {
+			// A duplicated @id, e.g. from a copied line; derive a new one below
+			d.lines[i].task.ID = ""
+			continue
+		}
+		used[line.task.ID] = true
+	}
+
+	for i, line := range d.lines {
+		if line.kind != lineTask || line.task.ID != "" {
+			continue
+		}
+		seed := line.category + "\x00" + strings.TrimSpace(line.text)
+		for n := 0; ; n++ {
+			id := model.DeriveTaskID(fmt.Sprintf("%s\x00%d", seed, n))
+			if !used[id] {
+				d.lines[i].task.ID = id
+				d.lines[i].derivedID = true
+				used[id] = true
+				break
+			}
+		}
+	}
+}
+
+// attachNotes turns the indented text directly below each task into the
+// task's notes. Blank lines between note paragraphs are part of the notes.
+func (d *document) attachNotes() {
+	for i := 0; i < len(d.lines); i++ {
+		if d.lines[i].kind != lineTask {
+			continue
+		}
+		task := &d.lines[i]
+		taskWidth := indentWidth(task.indent)
+
+		// Find the end of the notes, leaving out trailing blank lines
+		end := i + 1
+		for j := i + 1; j < len(d.lines); j++ {
+			line := d.lines[j]
+			if strings.TrimSpace(line.text) == "" && line.kind == lineText {
+				continue
+			}
+			if !isContinuation(line) || indentWidth(leadingSpace(line.text)) <= taskWidth {
+				break
+			}
+			end = j + 1
+		}
+		if end == i+1 {
+			continue
+		}
+
+		// The notes are stored without their common indentation
+		indent := ""
+		for j := i + 1; j < end; j++ {
+			if text := d.lines[j].text; strings.TrimSpace(text) != "" &&
+				(indent == "" || indentWidth(leadingSpace(text)) < indentWidth(indent)) {
+				indent = leadingSpace(text)
+			}
+		}
+
+		notes := make([]string, 0, end-i-1)
+		for j := i + 1; j < end; j++ {
+			d.lines[j].kind = lineNote
+			text := d.lines[j].text
+			if strings.HasPrefix(text, indent) {
+				text = text[len(indent):]
+			} else {
+				text = strings.TrimLeft(text, " \t")
+			}
+			notes = append(notes, strings.TrimRight(text, " \t"))
+		}
+		task.noteIndent = indent
+		task.task.Notes = strings.Join(notes, "\n")
+		i = end - 1
+	}
+}
+
+// noteLines renders a task's notes as lines below its task line, indented
+// as the task's notes were, or just past the task's list marker
+func (d *document) noteLines(task docLine, notes string) []docLine {
+	if notes == "" {
+		return nil
+	}
+
+	indent := task.noteIndent
+	if indentWidth(indent) <= indentWidth(task.indent) {
+		indent = task.indent + "  "
+	}
+
+	var lines []docLine
+	for _, text := range strings.Split(notes, "\n") {
+		if text != "" {
+			text = indent + text
+		}
+		lines = append(lines, docLine{kind: lineNote, text: text, eol: d.eol})
+	}
+	return lines
+}
+
+// leadingSpace returns the whitespace at the start of text
+func leadingSpace(text string) string {
+	return text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+}
+
+// linkSubtasks sets the parent of every task indented below another one.
+// Headings and unindented text end a list, so a task after them is never a
+// subtask of one before.
+func (d *document) linkSubtasks() {
+	var open []int // Lines of the tasks the next task may be nested in
+	unitFound := false
+
+	for i, line := range d.lines {
+		if line.kind != lineTask {
+			if startsBlock(line) {
+				open = nil
+			}
+			continue
+		}
+
+		width := indentWidth(line.indent)
+		for len(open) > 0 && indentWidth(d.lines[open[len(open)-1]].indent) >= width {
+			open = open[:len(open)-1]
+		}
+
+		if len(open) > 0 {
+			parent := d.lines[open[len(open)-1]]
+			d.lines[i].task.ParentID = parent.task.ID
+			d.lines[i].parent = parent.task.ID
+			if !unitFound && strings.HasPrefix(line.indent, parent.indent) {
+				d.indentUnit = line.indent[len(parent.indent):]
+				unitFound = true
+			}
+		}
+		open = append(open, i)
+	}
+}
+
+// startsBlock reports whether a line ends any list above it: a heading or
+// unindented text
+func startsBlock(line docLine) bool {
+	return line.kind == lineHeading ||
+		(line.kind == lineText && strings.TrimSpace(line.text) != "" && !isContinuation(line))
+}
+
+// indentWidth returns the width of leading whitespace, with tabs advancing to
+// the next multiple of four columns
+func indentWidth(indent string) int {
+	width := 0
+	for _, r := range indent {
+		if r == '\t' {
+			width += 4 - width%4
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// splitTaskLine recognizes a checklist item such as "  - [x] description"
+// and returns its parts
+func splitTaskLine(text string) (indent, marker string, done bool, rest string, ok bool) {
+	body := strings.TrimLeft(text, " \t")
+	indent = text[:len(text)-len(body)]
+
+	if len(body) < 6 || !strings.ContainsRune("-*+", rune(body[0])) ||
+		body[1] != ' ' || body[2] != '[' || body[4] != ']' {
+		return "", "", false, "", false
+	}
+
+	rest = strings.TrimSpace(body[5:])
+	if rest == "" {
+		return "", "", false, "", false
+	}
+
+	return indent, body[:1], body[3] == 'x' || body[3] == 'X', rest, true
+}
+
+// parseTaskText extracts the description and metadata from the text that
+// follows a task's checkbox
+func parseTaskText(text string, isDone bool, category string) model.Task {
+	description, fields := model.ParseMetadataFromText(text)
+
+	task := model.Task{
+		Description: description,
+		Done:        isDone,
+		Category:    category,
+		Metadata:    make(map[string]string),
+	}
+	task.ApplyMetadata(fields)
+
+	return task
+}
+
+// canonicalMetadataOrder is the order metadata is written in for keys that
+// weren't in the source line, such as those of newly created tasks
+var canonicalMetadataOrder = []string{"priority", "archived", "created", "completed", "start", "wait", "due", "every", "estimate", "spent", "timer", "blocked-by", "after", "tags", "status"}
+
+// formatTaskText renders a task's description and metadata as they appear
+// after the checkbox. Metadata keeps the order it was read in.
+func formatTaskText(task model.Task) string {
+	var text strings.Builder
+	text.WriteString(task.Description)
+
+	written := make(map[string]bool)
+	writeKey := func(key string) {
+		if written[key] {
+			return
+		}
+		written[key] = true
+
+		switch key {
+		case "priority":
+			if task.Priority != "" {
+				fmt.Fprintf(&text, " @priority:%s", task.Priority)
+				return
+			}
+		case "archived":
+			if task.Archived {
+				text.WriteString(" @archived:true")
+				return
+			}
+		case "created":
+			if !task.CreatedAt.IsZero() {
+				fmt.Fprintf(&text, " @created:%s", task.CreatedAt.UTC().Format(time.RFC3339))
+			}
+			return
+		case "completed":
+			if !task.CompletedAt.IsZero() {
+				fmt.Fprintf(&text, " @completed:%s", task.CompletedAt.UTC().Format(time.RFC3339))
+			}
+			return
+		case "tags":
+			for _, tag := range task.Tags() {
+				fmt.Fprintf(&text, " @tag:%s", tag)
+			}
+			return
+		case "id":
+			if task.ID != "" {
+				fmt.Fprintf(&text, " @id:%s", task.ID)
+			}
+			return
+		}
+
+		// Unknown keys, and known keys whose values tuiodo couldn't interpret
+		if value := task.Metadata[key]; value != "" {
+			fmt.Fprintf(&text, " @%s:%s", key, value)
+		}
+	}
+
+	for _, key := range task.MetadataOrder {
+		writeKey(key)
+	}
+	for _, key := range canonicalMetadataOrder {
+		writeKey(key)
+	}
+	for _, key := range task.MetadataKeys() {
+		writeKey(key)
+	}
+	writeKey("id")
+
+	return text.String()
+}
+
+// formatTaskLine renders a complete checklist line for a task
+func formatTaskLine(indent, marker string, task model.Task) string {
+	checkmark := " "
+	if task.Done {
+		checkmark = "x"
+	}
+	return fmt.Sprintf("%s%s [%s] %s", indent, marker, checkmark, formatTaskText(task))
+}
+
+// taskKey captures everything about a task that gets written to the file, so
+// tasks that didn't change can keep their original line
+func taskKey(task model.Task) string {
+	return task.Category + "\x00" + formatTaskLine("", "-", task)
+}
+
+// sectionCategory maps a task's category to the heading it is written under
+func sectionCategory(category string) string {
+	if category == "" {
+		return "Uncategorized"
+	}
+	return category
+}
+
+// tasks returns every task in the document in file order
+func (d *document) tasks() []model.Task {
+	tasks := make([]model.Task, 0, len(d.lines)/2)
+	for _, line := range d.lines {
+		if line.kind == lineTask {
+			tasks = append(tasks, line.task.Clone())
+		}
+	}
+	return tasks
+}
+
+// clone returns a copy of the document that can be updated without
+// changing this one
+func (d *document) clone() taskDocument {
+	c := *d
+	c.lines = make([]docLine, len(d.lines))
+	for i, line := range d.lines {
+		line.task = line.task.Clone()
+		c.lines[i] = line
+	}
+	return &c
+}
+
+// update reconciles the document with tasks, matching them to lines by ID.
+// Lines of unchanged tasks are kept exactly as they were, changed tasks are
+// rewritten in place, removed tasks are dropped and new tasks are appended
+// to their category's section. If anything changed, tasks whose IDs were
+// derived get an @id added to their line, so editing the line later doesn't
+// change the ID that other tasks and the activity log refer to.
+func (d *document) update(tasks []model.Task) {
+	before := d.render()
+	defer func() {
+		if !bytes.Equal(d.render(), before) {
+			d.writeDerivedIDs()
+		}
+	}()
+
+	byID := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		if task.ID != "" {
+			byID[task.ID] = i
+		}
+	}
+	placed := make([]bool, len(tasks))
+
+	var lines []docLine
+	keepNotes := false // Whether the notes of the last task line stay as they are
+	for _, line := range d.lines {
+		if line.kind == lineNote {
+			if keepNotes {
+				lines = append(lines, line)
+			}
+			continue
+		}
+		keepNotes = false
+
+		if line.kind != lineTask {
+			lines = append(lines, line)
+			continue
+		}
+
+		j, ok := byID[line.task.ID]
+		if !ok || placed[j] {
+			continue // Deleted
+		}
+		task := tasks[j]
+		if task.Category != line.category {
+			continue // Moved to another category; added to its new section below
+		}
+
+		placed[j] = true
+		if taskKey(task) != taskKey(line.task) {
+			line.text = formatTaskLine(line.indent, line.marker, task)
+			line.derivedID = false
+		}
+		notesChanged := task.Notes != line.task.Notes
+		line.task = task.Clone()
+		lines = append(lines, line)
+
+		if notesChanged {
+			lines = append(lines, d.noteLines(line, task.Notes)...)
+		} else {
+			keepNotes = true
+		}
+	}
+	d.lines = lines
+
+	// Tasks whose parent changed in a way indentation alone can't express
+	// are taken out and added again below their new parent, like new tasks
+	insert := d.nestSubtasks()
+	for i, task := range tasks {
+		if !placed[i] {
+			insert = append(insert, task)
+		}
+	}
+
+	// Parents go in before their subtasks so those can be placed below them
+	for len(insert) > 0 {
+		waiting := make(map[string]bool, len(insert))
+		for _, task := range insert {
+			waiting[task.ID] = true
+		}
+
+		var later []model.Task
+		for _, task := range insert {
+			if waiting[task.ParentID] {
+				later = append(later, task)
+			} else {
+				d.insertTask(task)
+			}
+		}
+		if len(later) == len(insert) {
+			// A parent cycle; give up on nesting these
+			for _, task := range later {
+				d.insertTask(task)
+			}
+			break
+		}
+		insert = later
+	}
+}
+
+// writeDerivedIDs adds an @id to the end of every task line whose ID was
+// derived, leaving the rest of the line as it is
+func (d *document) writeDerivedIDs() {
+	for i, line := range d.lines {
+		if line.kind == lineTask && line.derivedID {
+			d.lines[i].text = strings.TrimRight(line.text, " \t") + " @id:" + line.task.ID
+			d.lines[i].derivedID = false
+		}
+	}
+}
+
+// nestSubtasks re-indents task lines whose task has a different parent than
+// the file's indentation gives it. A task that can be nested under its
+// parent where it stands is re-indented in place; one that can't is removed
+// and returned to be inserted again.
+func (d *document) nestSubtasks() []model.Task {
+	category := make(map[string]string)
+	for _, line := range d.lines {
+		if line.kind == lineTask {
+			category[line.task.ID] = line.category
+		}
+	}
+
+	var moved []model.Task
+	var open []int // Lines of the tasks the next task may be nested in
+	lines := make([]docLine, 0, len(d.lines))
+	keepNotes := false
+
+	for _, line := range d.lines {
+		if line.kind == lineNote {
+			if keepNotes {
+				lines = append(lines, line)
+			}
+			continue
+		}
+		keepNotes = true
+
+		if line.kind != lineTask {
+			if startsBlock(line) {
+				open = nil
+			}
+			lines = append(lines, line)
+			continue
+		}
+
+		width := indentWidth(line.indent)
+		for len(open) > 0 && indentWidth(lines[open[len(open)-1]].indent) >= width {
+			open = open[:len(open)-1]
+		}
+
+		implied := ""
+		if len(open) > 0 {
+			implied = lines[open[len(open)-1]].task.ID
+		}
+
+		// A parent that isn't in the same section can't hold the task
+		want := line.task.ParentID
+		if parentCategory, ok := category[want]; !ok || parentCategory != line.category {
+			want = ""
+		}
+
+		// A task that moved to another parent is re-indented even where the
+		// old indentation happens to nest it correctly, to keep it tidy
+		if want != implied || want != line.parent {
+			at := -1
+			for i, idx := range open {
+				if lines[idx].task.ID == want {
+					at = i
+				}
+			}
+
+			switch {
+			case want == "":
+				line.indent = ""
+				open = nil
+			case at >= 0:
+				open = open[:at+1]
+				line.indent = lines[open[at]].indent + d.indentUnit
+			default:
+				moved = append(moved, line.task)
+				keepNotes = false
+				continue
+			}
+			line.text = formatTaskLine(line.indent, line.marker, line.task)
+			line.derivedID = false
+
+			// Notes follow their task to its new indentation
+			line.noteIndent = ""
+			keepNotes = false
+		}
+		line.parent = want
+
+		open = append(open, len(lines))
+		lines = append(lines, line)
+		if !keepNotes {
+			lines = append(lines, d.noteLines(line, line.task.Notes)...)
+		}
+	}
+
+	d.lines = lines
+	return moved
+}
+
+// insertTask adds a new task line at the end of its category's section,
+// creating the section if needed
+func (d *document) insertTask(task model.Task) {
+	line := docLine{
+		kind:     lineTask,
+		eol:      d.eol,
+		category: task.Category,
+		marker:   "-",
+		task:     task.Clone(),
+	}
+	line.text = formatTaskLine(line.indent, line.marker, task)
+
+	// Subtasks go after the last line below their parent
+	if task.ParentID != "" {
+		for i, parent := range d.lines {
+			if parent.kind != lineTask || parent.task.ID != task.ParentID || parent.category != task.Category {
+				continue
+			}
+
+			line.indent = parent.indent + d.indentUnit
+			line.marker = parent.marker
+			line.parent = parent.task.ID
+			insertAt := i + 1
+			for insertAt < len(d.lines) {
+				next := d.lines[insertAt]
+				if next.kind == lineTask && indentWidth(next.indent) > indentWidth(parent.indent) {
+					if insertAt == i+1 {
+						// Line up with the existing subtasks
+						line.indent = next.indent
+						line.marker = next.marker
+					}
+				} else if !isContinuation(next) {
+					break
+				}
+				insertAt++
+			}
+
+			line.text = formatTaskLine(line.indent, line.marker, task)
+			d.insertLines(insertAt, append([]docLine{line}, d.noteLines(line, task.Notes)...)...)
+			return
+		}
+	}
+
+	// Look for the section this task belongs in
+	start, end := d.findSection(task.Category)
+	if start < 0 && task.Category == "" {
+		task.Category = sectionCategory(task.Category)
+		start, end = d.findSection(task.Category)
+	}
+
+	if start < 0 {
+		d.appendSection(task.Category, append([]docLine{line}, d.noteLines(line, task.Notes)...))
+		return
+	}
+
+	// Insert after the last task of the section, or after the heading
+	insertAt := -1
+	for i := start; i < end; i++ {
+		if d.lines[i].kind == lineTask {
+			insertAt = i + 1
+		}
+	}
+	// Skip the indented lines that belong to that task
+	for insertAt > 0 && insertAt < end && isContinuation(d.lines[insertAt]) {
+		insertAt++
+	}
+	if insertAt < 0 {
+		insertAt = start
+		if d.lines[start].kind == lineHeading {
+			insertAt++
+			if insertAt < end && strings.TrimSpace(d.lines[insertAt].text) == "" {
+				insertAt++
+			}
+		}
+	}
+
+	newLines := append([]docLine{line}, d.noteLines(line, task.Notes)...)
+	if insertAt < len(d.lines) && d.lines[insertAt].kind == lineHeading {
+		// Keep a blank line between the task and the next heading
+		newLines = append(newLines, docLine{kind: lineText, eol: d.eol})
+	}
+	d.insertLines(insertAt, newLines...)
+}
+
+// isContinuation reports whether line is indented text that belongs to the
+// task above it, such as its notes
+func isContinuation(line docLine) bool {
+	if line.kind == lineNote {
+		return true
+	}
+	return line.kind == lineText && strings.TrimSpace(line.text) != "" &&
+		(strings.HasPrefix(line.text, " ") || strings.HasPrefix(line.text, "\t"))
+}
+
+// findSection returns the range of lines belonging to category. The lines
+// before the first heading form the section of the empty category, but only
+// when they already contain tasks. start is -1 if there is no such section.
+func (d *document) findSection(category string) (start, end int) {
+	start = -1
+	for i, line := range d.lines {
+		if line.kind != lineHeading {
+			continue
+		}
+		if start >= 0 {
+			return start, i
+		}
+		if line.category == category && category != "" {
+			start = i
+		}
+	}
+	if start >= 0 {
+		return start, len(d.lines)
+	}
+
+	if category == "" {
+		for i, line := range d.lines {
+			if line.kind == lineHeading {
+				break
+			}
+			if line.kind == lineTask {
+				end = i + 1
+				start = 0
+			}
+		}
+	}
+	return start, end
+}
+
+// appendSection adds a new category section holding the lines of a task at
+// the end of the document, above the tuiodo footer if there is one
+func (d *document) appendSection(category string, task []docLine) {
+	insertAt := len(d.lines)
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(d.lines[i].text) == footerComment {
+			insertAt = i
+			break
+		}
+	}
+	// Step back over blank lines so the footer keeps its spacing
+	for insertAt > 0 && insertAt < len(d.lines) && strings.TrimSpace(d.lines[insertAt-1].text) == "" {
+		insertAt--
+	}
+
+	var newLines []docLine
+	if insertAt > 0 && strings.TrimSpace(d.lines[insertAt-1].text) != "" {
+		newLines = append(newLines, docLine{kind: lineText, eol: d.eol})
+	}
+	newLines = append(newLines,
+		docLine{kind: lineHeading, text: "## " + category, eol: d.eol, category: category},
+		docLine{kind: lineText, eol: d.eol},
+	)
+	newLines = append(newLines, task...)
+	if insertAt < len(d.lines) && strings.TrimSpace(d.lines[insertAt].text) != "" {
+		newLines = append(newLines, docLine{kind: lineText, eol: d.eol})
+	}
+
+	d.insertLines(insertAt, newLines...)
+}
+
+// insertLines inserts lines before index i
+func (d *document) insertLines(i int, lines ...docLine) {
+	// The line we insert after may have been the unterminated last line
+	if i > 0 && i == len(d.lines) && d.lines[i-1].eol == "" {
+		d.lines[i-1].eol = d.eol
+	}
+	d.lines = append(d.lines[:i], append(lines, d.lines[i:]...)...)
+}
+
+// bytes renders the document back into file content, adding the tuiodo
+// footer to a new file
+func (d *document) bytes() []byte {
+	if d.isNew && len(d.lines) > 0 {
+		d.insertLines(len(d.lines),
+			docLine{kind: lineText, eol: d.eol},
+			docLine{kind: lineText, text: footerComment, eol: d.eol},
+		)
+		d.isNew = false
+	}
+	return d.render()
+}
+
+// render returns the lines of the document as file content
+func (d *document) render() []byte {
+	var buf bytes.Buffer
+	if d.bom {
+		buf.WriteString(utf8BOM)
+	}
+	for i, line := range d.lines {
+		buf.WriteString(line.text)
+		if line.eol == "" && i < len(d.lines)-1 {
+			buf.WriteString(d.eol)
+		} else {
+			buf.WriteString(line.eol)
+		}
+	}
+	return buf.Bytes()
+}
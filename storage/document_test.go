@@ -0,0 +1,334 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+const sampleTodo = "\xef\xbb\xbf# Project notes\r\n" +
+	"\r\n" +
+	"Some prose that tuiodo doesn't understand, with a [link](https://example.com).\r\n" +
+	"\r\n" +
+	"## Work\r\n" +
+	"\r\n" +
+	"- [ ] Write report @priority:high @created:2025-03-14T10:00:00Z\r\n" +
+	"  - nested bullet\r\n" +
+	"- [x] Send email   @created:2025-03-14T11:00:00Z\r\n" +
+	"\r\n" +
+	"### Later\r\n" +
+	"\r\n" +
+	"## Home\r\n" +
+	"\r\n" +
+	"* [ ] Water plants @created:2025-03-14T12:00:00Z\r\n" +
+	"- [ ] Fix sink @created:2025-03-14T13:00:00Z"
+
+func TestDocumentRoundTripIsByteIdentical(t *testing.T) {
+	doc := parseDocument([]byte(sampleTodo))
+	doc.update(doc.tasks())
+
+	if got := string(doc.bytes()); got != sampleTodo {
+		t.Errorf("Round trip changed the file:\n got: %q\nwant: %q", got, sampleTodo)
+	}
+}
+
+func TestDocumentKeepsOrderWhenTasksAreSorted(t *testing.T) {
+	doc := parseDocument([]byte(sampleTodo))
+	tasks := doc.tasks()
+
+	// Sorting in the model must not reorder the file
+	m := model.NewModel(tasks)
+	m.SortTasks(model.SortByCategory)
+	doc.update(m.Tasks)
+
+	if got := string(doc.bytes()); got != sampleTodo {
+		t.Errorf("Sorting changed the file:\n got: %q\nwant: %q", got, sampleTodo)
+	}
+}
+
+func TestDocumentRewritesOnlyChangedLines(t *testing.T) {
+	doc := parseDocument([]byte(sampleTodo))
+	tasks := doc.tasks()
+
+	ids := make(map[string]string)
+	for i := range tasks {
+		if tasks[i].Description == "Water plants" {
+			tasks[i].Done = true
+		}
+		ids[tasks[i].Description] = tasks[i].ID
+	}
+	doc.update(tasks)
+
+	// The changed line is rewritten; the others are untouched apart from
+	// the @id every task gains the first time the file changes
+	want := strings.NewReplacer(
+		"* [ ] Water plants @created:2025-03-14T12:00:00Z",
+		"* [x] Water plants @created:2025-03-14T12:00:00Z @id:"+ids["Water plants"],
+		"@priority:high @created:2025-03-14T10:00:00Z",
+		"@priority:high @created:2025-03-14T10:00:00Z @id:"+ids["Write report"],
+		"Send email   @created:2025-03-14T11:00:00Z",
+		"Send email   @created:2025-03-14T11:00:00Z @id:"+ids["Send email"],
+		"Fix sink @created:2025-03-14T13:00:00Z",
+		"Fix sink @created:2025-03-14T13:00:00Z @id:"+ids["Fix sink"],
+	).Replace(sampleTodo)
+	if got := string(doc.bytes()); got != want {
+		t.Errorf("Unexpected output:\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestDocumentAddsAndRemovesTasks(t *testing.T) {
+	doc := parseDocument([]byte(sampleTodo))
+
+	var tasks []model.Task
+	ids := make(map[string]string)
+	for _, task := range doc.tasks() {
+		if task.Description != "Send email" {
+			tasks = append(tasks, task)
+		}
+		ids[task.Description] = task.ID
+	}
+	created := time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)
+	tasks = append(tasks,
+		model.Task{Description: "Review PR", Category: "Work", CreatedAt: created},
+		model.Task{Description: "Plan trip", Category: "Travel", CreatedAt: created},
+	)
+	doc.update(tasks)
+
+	got := string(doc.bytes())
+
+	if strings.Contains(got, "Send email") {
+		t.Errorf("Deleted task is still present:\n%s", got)
+	}
+
+	// The new Work task follows the last Work task, before the unknown heading
+	if !strings.Contains(got, "@id:"+ids["Write report"]+"\r\n  - nested bullet\r\n- [ ] Review PR @created:2025-03-15T09:00:00Z\r\n\r\n### Later") {
+		t.Errorf("New task not placed in its section:\n%q", got)
+	}
+
+	// The new category is added at the end with the file's line endings
+	if !strings.HasSuffix(got, "- [ ] Fix sink @created:2025-03-14T13:00:00Z @id:"+ids["Fix sink"]+"\r\n\r\n## Travel\r\n\r\n- [ ] Plan trip @created:2025-03-15T09:00:00Z\r\n") {
+		t.Errorf("New section not appended:\n%q", got)
+	}
+
+	if !strings.HasPrefix(got, utf8BOM+"# Project notes\r\n") {
+		t.Errorf("Preamble or BOM was lost:\n%q", got)
+	}
+}
+
+func TestNewDocumentHasFooter(t *testing.T) {
+	doc := newDocument()
+	created := time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)
+	doc.update([]model.Task{{Description: "First", Category: "Work", CreatedAt: created}})
+
+	want := "## Work\n\n- [ ] First @created:2025-03-15T09:00:00Z\n\n" + footerComment + "\n"
+	if got := string(doc.bytes()); got != want {
+		t.Errorf("Unexpected new file:\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestDocumentPreservesUnknownMetadata(t *testing.T) {
+	input := "## Work\n\n- [x] Ship it   @completed:2025-08-23T04:38:56Z @priority:high @estimate:2h @created:2025-08-18T23:36:06Z\n"
+	doc := parseDocument([]byte(input))
+
+	tasks := doc.tasks()
+	if len(tasks) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(tasks))
+	}
+	task := tasks[0]
+	if task.Description != "Ship it" {
+		t.Errorf("Expected metadata to be stripped from description, got %q", task.Description)
+	}
+	if task.Metadata["estimate"] != "2h" {
+		t.Errorf("Unknown metadata not parsed: %v", task.Metadata)
+	}
+	if !task.CompletedAt.Equal(time.Date(2025, 8, 23, 4, 38, 56, 0, time.UTC)) {
+		t.Errorf("Expected the completion time to be parsed, got %v", task.CompletedAt)
+	}
+	if task.Priority != model.PriorityHigh {
+		t.Errorf("Expected high priority, got %q", task.Priority)
+	}
+
+	// Editing the task keeps every field in its original order
+	tasks[0].Description = "Ship it now"
+	doc.update(tasks)
+
+	want := "## Work\n\n- [x] Ship it now @completed:2025-08-23T04:38:56Z @priority:high @estimate:2h @created:2025-08-18T23:36:06Z @id:" + tasks[0].ID + "\n"
+	if got := string(doc.bytes()); got != want {
+		t.Errorf("Unexpected output:\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestDocumentIDsAreStableAndMatchTasks(t *testing.T) {
+	input := "## Work\n\n- [ ] Same @created:2025-03-14T10:00:00Z\n- [ ] Same @created:2025-03-14T10:00:00Z\n"
+
+	first := parseDocument([]byte(input)).tasks()
+	second := parseDocument([]byte(input)).tasks()
+	if first[0].ID == first[1].ID {
+		t.Fatalf("Identical tasks got the same ID %q", first[0].ID)
+	}
+	if first[0].ID != second[0].ID || first[1].ID != second[1].ID {
+		t.Errorf("Derived IDs changed between loads: %v vs %v", []string{first[0].ID, first[1].ID}, []string{second[0].ID, second[1].ID})
+	}
+
+	// Completing the second of two identical tasks rewrites only its line,
+	// and the first keeps its ID
+	doc := parseDocument([]byte(input))
+	tasks := doc.tasks()
+	tasks[0], tasks[1] = tasks[1], tasks[0]
+	tasks[0].Done = true
+	doc.update(tasks)
+
+	want := "## Work\n\n- [ ] Same @created:2025-03-14T10:00:00Z @id:" + tasks[1].ID + "\n- [x] Same @created:2025-03-14T10:00:00Z @id:" + tasks[0].ID + "\n"
+	if got := string(doc.bytes()); got != want {
+		t.Errorf("Unexpected output:\n got: %q\nwant: %q", got, want)
+	}
+
+	// The persisted ID is read back
+	reloaded := parseDocument([]byte(want)).tasks()
+	if reloaded[1].ID != tasks[0].ID {
+		t.Errorf("Expected persisted ID %q, got %q", tasks[0].ID, reloaded[1].ID)
+	}
+}
+
+func TestDocumentReferencedTaskKeepsItsID(t *testing.T) {
+	input := "## Work\n\n- [ ] alpha\n- [ ] beta\n"
+	doc := parseDocument([]byte(input))
+	tasks := doc.tasks()
+
+	// beta starts waiting for alpha, whose line isn't touched otherwise
+	tasks[1].Metadata["blocked-by"] = tasks[0].ID
+	doc.update(tasks)
+	saved := string(doc.bytes())
+	if !strings.Contains(saved, "- [ ] alpha @id:"+tasks[0].ID+"\n") {
+		t.Fatalf("Expected alpha's ID to be written, got:\n%s", saved)
+	}
+
+	// Fixing a typo in alpha's line by hand keeps the reference working
+	edited := strings.Replace(saved, "- [ ] alpha", "- [ ] Alpha", 1)
+	reloaded := parseDocument([]byte(edited)).tasks()
+	if reloaded[0].ID != tasks[0].ID || reloaded[1].Blockers()[0] != reloaded[0].ID {
+		t.Errorf("Expected beta to still wait for alpha (%s), got %+v", tasks[0].ID, reloaded)
+	}
+}
+
+func TestDocumentSubtasksFollowIndentation(t *testing.T) {
+	const todo = "## Work\n" +
+		"\n" +
+		"- [ ] Release @id:aaaaaa\n" +
+		"    - [x] Write notes @id:bbbbbb\n" +
+		"        - [ ] Proofread @id:cccccc\n" +
+		"    - [ ] Tag build @id:dddddd\n" +
+		"- [ ] Unrelated @id:eeeeee\n"
+
+	doc := parseDocument([]byte(todo))
+	parents := make(map[string]string)
+	for _, task := range doc.tasks() {
+		parents[task.ID] = task.ParentID
+	}
+	want := map[string]string{"aaaaaa": "", "bbbbbb": "aaaaaa", "cccccc": "bbbbbb", "dddddd": "aaaaaa", "eeeeee": ""}
+	for id, parent := range want {
+		if parents[id] != parent {
+			t.Errorf("Task %s: expected parent %q, got %q", id, parent, parents[id])
+		}
+	}
+
+	doc.update(doc.tasks())
+	if got := string(doc.bytes()); got != todo {
+		t.Fatalf("Round trip changed the file:\n got: %q\nwant: %q", got, todo)
+	}
+
+	// A new subtask lines up with its siblings, below the last of them
+	m := model.NewModel(doc.tasks())
+	m.AddSubtask("aaaaaa", "Announce", model.PriorityNone, nil)
+	doc.update(m.Tasks)
+	got := string(doc.bytes())
+	if !strings.Contains(got, "    - [ ] Tag build @id:dddddd\n    - [ ] Announce @priority:low") {
+		t.Errorf("New subtask not placed below its parent:\n%s", got)
+	}
+
+	// Deleting a parent moves its subtasks up a level
+	m.DeleteTask("bbbbbb")
+	doc.update(m.Tasks)
+	got = string(doc.bytes())
+	if !strings.Contains(got, "- [ ] Release @id:aaaaaa\n    - [ ] Proofread @id:cccccc\n    - [ ] Tag build") {
+		t.Errorf("Subtask not moved up after its parent was deleted:\n%s", got)
+	}
+
+	reparsed := parseDocument([]byte(got)).tasks()
+	for _, task := range reparsed {
+		if task.ID == "cccccc" && task.ParentID != "aaaaaa" {
+			t.Errorf("Expected the moved subtask to reload under its new parent, got %q", task.ParentID)
+		}
+	}
+}
+
+func TestDocumentNotes(t *testing.T) {
+	const todo = "## Work\n" +
+		"\n" +
+		"- [ ] Fix login @id:aaaaaa\n" +
+		"   Steps to reproduce:\n" +
+		"\n" +
+		"     1. Log out\n" +
+		"   See https://example.com/issue/1\n" +
+		"  - [ ] Write test @id:bbbbbb\n" +
+		"\n" +
+		"Unrelated paragraph\n"
+
+	doc := parseDocument([]byte(todo))
+	tasks := doc.tasks()
+	want := "Steps to reproduce:\n\n  1. Log out\nSee https://example.com/issue/1"
+	if tasks[0].Notes != want {
+		t.Errorf("Unexpected notes:\n got: %q\nwant: %q", tasks[0].Notes, want)
+	}
+	if tasks[1].Notes != "" || tasks[1].ParentID != "aaaaaa" {
+		t.Errorf("Expected the subtask without notes below its parent, got %+v", tasks[1])
+	}
+
+	doc.update(tasks)
+	if got := string(doc.bytes()); got != todo {
+		t.Fatalf("Round trip changed the file:\n got: %q\nwant: %q", got, todo)
+	}
+
+	// Edited notes are written back with the same indentation
+	tasks[0].Notes = "Fixed in v2\n\nCheck again"
+	tasks[1].Notes = "Use the staging server"
+	doc.update(tasks)
+	wantFile := "## Work\n" +
+		"\n" +
+		"- [ ] Fix login @id:aaaaaa\n" +
+		"   Fixed in v2\n" +
+		"\n" +
+		"   Check again\n" +
+		"  - [ ] Write test @id:bbbbbb\n" +
+		"    Use the staging server\n" +
+		"\n" +
+		"Unrelated paragraph\n"
+	if got := string(doc.bytes()); got != wantFile {
+		t.Errorf("Unexpected output:\n got: %q\nwant: %q", got, wantFile)
+	}
+
+	// Deleting a task deletes its notes
+	doc.update(tasks[1:])
+	if got := string(doc.bytes()); strings.Contains(got, "Fixed in v2") {
+		t.Errorf("Notes of a deleted task are left behind:\n%s", got)
+	}
+}
+
+func TestDocumentRecurringTaskRoundTrips(t *testing.T) {
+	input := "## Chores\n\n- [ ] Review invoices @due:2099-01-31 @every:monthly @id:aaaaaa\n"
+	doc := parseDocument([]byte(input))
+
+	m := model.NewModel(doc.tasks())
+	m.ToggleTask("aaaaaa")
+	next := m.Tasks[m.TaskIndexByID(m.NextOccurrenceID)]
+	doc.update(m.Tasks)
+
+	want := "## Chores\n\n" +
+		"- [x] Review invoices @due:2099-01-31 @id:aaaaaa @completed:" + m.Tasks[0].CompletedAt.Format(time.RFC3339) + "\n" +
+		"- [ ] Review invoices @due:2099-02-28 @every:monthly @id:" + next.ID + " @created:" + next.CreatedAt.Format(time.RFC3339) + "\n"
+	if got := string(doc.bytes()); got != want {
+		t.Errorf("Unexpected output:\n got: %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+// taskDocument is a parsed task file that can be written back
+type taskDocument interface {
+	tasks() []model.Task
+	update(tasks []model.Task)
+	bytes() []byte
+	clone() taskDocument
+}
+
+// diskState fingerprints a task file as it was last read or written
+type diskState struct {
+	modTime time.Time
+	size    int64
+	sum     [sha1.Size]byte
+	known   bool
+}
+
+// fileBackend is a Backend that keeps the tasks in a single file. The file
+// format is supplied by parse and empty; writes are atomic and serialized with
+// other tuiodo processes through an advisory file lock.
+type fileBackend struct {
+	path  string
+	ext   string // extension of backup files
+	parse func(data []byte) taskDocument
+	empty func() taskDocument
+
+	mu        sync.Mutex   // guards doc, disk and backupDir
+	doc       taskDocument // the file as of the last load or save
+	disk      diskState
+	backupDir string // where the next Save backs up the file; see backupBeforeSave
+}
+
+// NewMarkdownBackend returns a backend storing tasks as a Markdown checklist.
+// Everything in the file that isn't a task is kept as written.
+func NewMarkdownBackend(path string) Backend {
+	return &fileBackend{
+		path:  path,
+		ext:   ".md",
+		parse: func(data []byte) taskDocument { return parseDocument(data) },
+		empty: func() taskDocument { return newDocument() },
+	}
+}
+
+// Path returns the task file's path
+func (b *fileBackend) Path() string {
+	return b.path
+}
+
+// Load reads and parses the task file
+func (b *fileBackend) Load() ([]model.Task, error) {
+	content, err := os.ReadFile(b.path)
+	if os.IsNotExist(err) {
+		content, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc := b.empty()
+	if content != nil {
+		doc = b.parse(content)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.doc = doc
+	b.remember(content)
+	return doc.tasks(), nil
+}
+
+// Loaded returns the tasks as of the last load or save
+func (b *fileBackend) Loaded() []model.Task {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.doc == nil {
+		return nil
+	}
+	return b.doc.tasks()
+}
+
+// Save writes tasks to the file. Only the tasks that changed are rewritten;
+// everything else in the file is left untouched.
+func (b *fileBackend) Save(tasks []model.Task) error {
+	lock, err := acquireLock(b.path)
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %w", b.path, err)
+	}
+	defer lock.release()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	backupDir := b.backupDir
+	b.backupDir = ""
+
+	// Refuse to overwrite changes we haven't seen yet
+	if b.changed() {
+		return ErrFileChanged
+	}
+	if backupDir != "" {
+		if err := b.Backup(backupDir); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", b.path, err)
+		}
+	}
+
+	// Start from a copy of the document we loaded, or the file on disk if we
+	// never did. The loaded document stays as it is until the write succeeds.
+	var doc taskDocument
+	if b.doc != nil {
+		doc = b.doc.clone()
+	} else if content, err := os.ReadFile(b.path); err == nil {
+		doc = b.parse(content)
+	} else {
+		doc = b.empty()
+	}
+
+	doc.update(tasks)
+	data := doc.bytes()
+	if err := writeFileAtomic(b.path, data); err != nil {
+		return err
+	}
+
+	b.doc = doc
+	b.remember(data)
+	return nil
+}
+
+// Watch polls the file for changes made outside this backend
+func (b *fileBackend) Watch(interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			b.mu.Lock()
+			changed := b.changed()
+			b.mu.Unlock()
+
+			if !changed {
+				continue
+			}
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changes
+}
+
+// Backup copies the file into dir as TODO-<source>-<timestamp>, where source
+// identifies the file (see backupSource), with the extension of the file's
+// format, numbering backups taken within the same second and
+// gzip-compressing them if backups are configured to be compressed. A file
+// that doesn't exist yet has nothing to back up.
+func (b *fileBackend) Backup(dir string) error {
+	data, err := os.ReadFile(b.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	backupFile := filepath.Join(dir, backupFileName(dir, backupSource(b.path), time.Now(), b.ext))
+	if !compressBackups {
+		return os.WriteFile(backupFile, data, 0644)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(backupFile+".gz", buf.Bytes(), 0644)
+}
+
+// backupBeforeSave has the next Save back up the file into dir, once it
+// knows the file wasn't changed outside and the save goes ahead
+func (b *fileBackend) backupBeforeSave(dir string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.backupDir = dir
+}
+
+// remember records content as the current state of the file. The caller
+// must hold b.mu.
+func (b *fileBackend) remember(content []byte) {
+	b.disk = diskState{sum: sha1.Sum(content), known: true}
+	if info, err := os.Stat(b.path); err == nil {
+		b.disk.modTime = info.ModTime()
+		b.disk.size = info.Size()
+	}
+}
+
+// changedOutside reports whether the file behind a file backend was changed
+// by something else since it was last read or written. Other backends are
+// never changed outside.
+func changedOutside(backend Backend) bool {
+	b, ok := backend.(*fileBackend)
+	if !ok {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.changed()
+}
+
+// changed reports whether the file differs from how it was last read or
+// written. A missing file is not treated as a change, since editors and git
+// often remove a file briefly before writing the new one. The caller must
+// hold b.mu.
+func (b *fileBackend) changed() bool {
+	last := b.disk
+	if !last.known {
+		return false
+	}
+
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return false
+	}
+	if info.ModTime().Equal(last.modTime) && info.Size() == last.size {
+		return false
+	}
+
+	content, err := os.ReadFile(b.path)
+	if err != nil {
+		return false
+	}
+	if sha1.Sum(content) == last.sum {
+		// Touched but not modified; remember the new timestamp so the file
+		// isn't read again on every poll
+		b.disk.modTime = info.ModTime()
+		b.disk.size = info.Size()
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+// jsonlTask is how a task is encoded on one line of a JSON Lines file
+type jsonlTask struct {
+	ID          string         `json:"id"`
+	Parent      string         `json:"parent,omitempty"`
+	Description string         `json:"description"`
+	Notes       string         `json:"notes,omitempty"`
+	Done        bool           `json:"done"`
+	Category    string         `json:"category,omitempty"`
+	Priority    model.Priority `json:"priority,omitempty"`
+	Created     *time.Time     `json:"created,omitempty"`
+	Completed   *time.Time     `json:"completed,omitempty"`
+	Archived    bool           `json:"archived,omitempty"`
+	Metadata    jsonlMetadata  `json:"metadata,omitempty"`
+}
+
+// jsonlMetadata is a task's metadata as a JSON object that keeps its key order
+type jsonlMetadata []model.MetadataField
+
+// MarshalJSON writes the fields as an object in order
+func (md jsonlMetadata) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range md {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.Key)
+		value, _ := json.Marshal(field.Value)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON reads an object of string values, keeping the key order
+func (md *jsonlMetadata) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("metadata must be an object")
+	}
+
+	*md = nil
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var value string
+		if err := dec.Decode(&value); err != nil {
+			return fmt.Errorf("metadata %q: %w", tok, err)
+		}
+		*md = append(*md, model.MetadataField{Key: tok.(string), Value: value})
+	}
+	return nil
+}
+
+// jsonlLine is one line of a JSON Lines task file. Lines that aren't valid
+// tasks (blank lines, for example) are kept as they are.
+type jsonlLine struct {
+	raw     string
+	task    *model.Task
+	derived bool // Whether the task's ID was derived and isn't in the line yet
+}
+
+// jsonlDocument is a parsed JSON Lines task file with one task per line
+type jsonlDocument struct {
+	lines []jsonlLine
+}
+
+// NewJSONLBackend returns a backend storing one JSON object per task per line,
+// for setups where other programs read and write the tasks
+func NewJSONLBackend(path string) Backend {
+	return &fileBackend{
+		path:  path,
+		ext:   ".jsonl",
+		parse: func(data []byte) taskDocument { return parseJSONLDocument(data) },
+		empty: func() taskDocument { return &jsonlDocument{} },
+	}
+}
+
+// parseJSONLDocument parses a JSON Lines task file
+func parseJSONLDocument(data []byte) *jsonlDocument {
+	doc := &jsonlDocument{}
+
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return doc
+	}
+
+	used := make(map[string]bool)
+	var unnamed []int
+	for _, raw := range strings.Split(text, "\n") {
+		line := jsonlLine{raw: raw}
+
+		var record jsonlTask
+		if err := json.Unmarshal([]byte(raw), &record); err == nil {
+			task := decodeJSONLTask(record)
+			line.task = &task
+			if task.ID == "" || used[task.ID] {
+				unnamed = append(unnamed, len(doc.lines))
+			} else {
+				used[task.ID] = true
+			}
+		}
+
+		doc.lines = append(doc.lines, line)
+	}
+
+	// Tasks without an ID get one derived from their line, as in Markdown,
+	// which update writes once the file is saved with changes
+	for _, i := range unnamed {
+		task := doc.lines[i].task
+		seed := formatJSONLTask(*task)
+		for n := 0; ; n++ {
+			id := model.DeriveTaskID(fmt.Sprintf("%s\x00%d", seed, n))
+			if !used[id] {
+				task.ID = id
+				used[id] = true
+				break
+			}
+		}
+		doc.lines[i].derived = true
+	}
+
+	return doc
+}
+
+// decodeJSONLTask converts a decoded line to a task
+func decodeJSONLTask(record jsonlTask) model.Task {
+	task := model.Task{
+		ID:          record.ID,
+		ParentID:    record.Parent,
+		Description: record.Description,
+		Notes:       record.Notes,
+		Done:        record.Done,
+		Category:    record.Category,
+		Priority:    record.Priority,
+		Archived:    record.Archived,
+	}
+	if record.Created != nil {
+		task.CreatedAt = *record.Created
+	}
+	if record.Completed != nil {
+		task.CompletedAt = *record.Completed
+	}
+	for _, field := range record.Metadata {
+		task.SetMetadata(field.Key, field.Value)
+	}
+	return task
+}
+
+// formatJSONLTask encodes a task as a single line
+func formatJSONLTask(task model.Task) string {
+	record := jsonlTask{
+		ID:          task.ID,
+		Parent:      task.ParentID,
+		Description: task.Description,
+		Notes:       task.Notes,
+		Done:        task.Done,
+		Category:    task.Category,
+		Priority:    task.Priority,
+		Archived:    task.Archived,
+	}
+	if !task.CreatedAt.IsZero() {
+		created := task.CreatedAt.UTC()
+		record.Created = &created
+	}
+	if !task.CompletedAt.IsZero() {
+		completed := task.CompletedAt.UTC()
+		record.Completed = &completed
+	}
+	for _, key := range task.MetadataKeys() {
+		record.Metadata = append(record.Metadata, model.MetadataField{Key: key, Value: task.Metadata[key]})
+	}
+
+	// Encoding a struct of strings, bools and a time can't fail
+	data, _ := json.Marshal(record)
+	return string(data)
+}
+
+// tasks returns the tasks in the file
+func (d *jsonlDocument) tasks() []model.Task {
+	tasks := make([]model.Task, 0, len(d.lines))
+	for _, line := range d.lines {
+		if line.task != nil {
+			tasks = append(tasks, line.task.Clone())
+		}
+	}
+	return tasks
+}
+
+// clone returns a copy of the document that can be updated without
+// changing this one
+func (d *jsonlDocument) clone() taskDocument {
+	c := &jsonlDocument{lines: make([]jsonlLine, len(d.lines))}
+	for i, line := range d.lines {
+		if line.task != nil {
+			task := line.task.Clone()
+			line.task = &task
+		}
+		c.lines[i] = line
+	}
+	return c
+}
+
+// update replaces the tasks in the file with tasks, matched by ID. Unchanged
+// tasks keep their line as written, changed ones are re-encoded in place, and
+// new tasks are appended. If anything changed, tasks whose IDs were derived
+// are re-encoded too, so their IDs are written to the file.
+func (d *jsonlDocument) update(tasks []model.Task) {
+	before := d.bytes()
+	defer func() {
+		if bytes.Equal(d.bytes(), before) {
+			return
+		}
+		for i, line := range d.lines {
+			if line.derived {
+				d.lines[i].raw = formatJSONLTask(*line.task)
+				d.lines[i].derived = false
+			}
+		}
+	}()
+
+	byID := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		byID[task.ID] = i
+	}
+
+	placed := make(map[string]bool, len(tasks))
+	lines := make([]jsonlLine, 0, len(d.lines)+len(tasks))
+	for _, line := range d.lines {
+		if line.task == nil {
+			lines = append(lines, line)
+			continue
+		}
+
+		i, ok := byID[line.task.ID]
+		if !ok || placed[line.task.ID] {
+			// Deleted
+			continue
+		}
+		placed[line.task.ID] = true
+
+		task := tasks[i].Clone()
+		if !model.SameTask(*line.task, task) {
+			line.raw = formatJSONLTask(task)
+			line.derived = false
+		}
+		line.task = &task
+		lines = append(lines, line)
+	}
+
+	for _, task := range tasks {
+		if placed[task.ID] {
+			continue
+		}
+		placed[task.ID] = true
+
+		task = task.Clone()
+		lines = append(lines, jsonlLine{raw: formatJSONLTask(task), task: &task})
+	}
+
+	d.lines = lines
+}
+
+// bytes renders the file, one line per task, each ending in a newline
+func (d *jsonlDocument) bytes() []byte {
+	var buf bytes.Buffer
+	for _, line := range d.lines {
+		buf.WriteString(line.raw)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+func TestJSONLBackendRoundTrip(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tuiodo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "TODO.jsonl")
+	original := `{"id":"aaaaaa","description":"Write report","done":false,"category":"Work","priority":"high","metadata":{"due":"2025-03-20","owner":"sam"}}
+{"description": "Written by hand", "done": true}
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	backend := NewJSONLBackend(path)
+	tasks, err := backend.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", len(tasks))
+	}
+	if tasks[0].Priority != model.PriorityHigh || tasks[0].Metadata["owner"] != "sam" {
+		t.Errorf("Task not decoded: %+v", tasks[0])
+	}
+	if keys := tasks[0].MetadataKeys(); len(keys) != 2 || keys[0] != "due" {
+		t.Errorf("Expected metadata in file order, got %v", keys)
+	}
+	if tasks[1].ID == "" || !tasks[1].Done {
+		t.Errorf("Expected hand-written task with a derived ID, got %+v", tasks[1])
+	}
+
+	// Saving unchanged tasks leaves the file as it was
+	if err := backend.Save(tasks); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("Unchanged save rewrote the file:\n%s", data)
+	}
+
+	// Changed tasks are re-encoded in place, new ones appended, and the
+	// derived ID is written
+	tasks[0].Done = true
+	tasks = append(tasks, model.Task{ID: "bbbbbb", Description: "New", CreatedAt: time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)})
+	if err := backend.Save(tasks); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	want := []string{
+		`{"id":"aaaaaa","description":"Write report","done":true,"category":"Work","priority":"high","metadata":{"due":"2025-03-20","owner":"sam"}}`,
+		`{"id":"` + tasks[1].ID + `","description":"Written by hand","done":true}`,
+		`{"id":"bbbbbb","description":"New","done":false,"created":"2025-03-14T10:00:00Z"}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d lines, got:\n%s", len(want), data)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("Line %d:\nwant %s\ngot  %s", i, want[i], lines[i])
+		}
+	}
+}
+
+func TestSaveTasksWithMemoryBackend(t *testing.T) {
+	defer SetBackend(NewMarkdownBackend(DefaultTodoFilePath))
+
+	memory := NewMemoryBackend([]model.Task{{ID: "aaaaaa", Description: "First"}})
+	SetBackend(memory)
+
+	tasks := LoadTasks()
+	tasks[0].Done = true
+	if err := SaveTasks(tasks); err != nil {
+		t.Fatalf("SaveTasks failed: %v", err)
+	}
+	if stored := memory.Stored(); !stored[0].Done {
+		t.Errorf("Expected saved task to be stored, got %+v", stored)
+	}
+
+	changes := Watch(time.Second)
+	memory.Replace([]model.Task{{ID: "aaaaaa", Description: "Edited elsewhere"}})
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("Replace was not reported")
+	}
+	if err := SaveTasks(tasks); err != ErrFileChanged {
+		t.Errorf("Expected ErrFileChanged, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// fileLock is an advisory lock shared by every tuiodo process that writes
+// the same TODO file, so concurrent instances queue up instead of clobbering
+// each other's writes.
+type fileLock struct {
+	f *os.File
+}
+
+// lockPath returns the lock file used for the TODO file at path. Locks live
+// in the user cache directory, keyed by the resolved file path, so they don't
+// clutter the repository and every symlink to the same file shares one lock.
+func lockPath(path string) string {
+	if target, err := resolveTarget(path); err == nil {
+		path = target
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	sum := sha1.Sum([]byte(path))
+	name := hex.EncodeToString(sum[:8]) + ".lock"
+
+	lockDir := filepath.Join(os.TempDir(), "tuiodo-locks")
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		lockDir = filepath.Join(cacheDir, "tuiodo", "locks")
+	}
+
+	return filepath.Join(lockDir, name)
+}
+
+// acquireLock blocks until this process holds the lock for the TODO file at path
+func acquireLock(path string) (*fileLock, error) {
+	lp := lockPath(path)
+	if err := os.MkdirAll(filepath.Dir(lp), 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(lp, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &fileLock{f: f}, nil
+}
+
+// release gives up the lock
+func (l *fileLock) release() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	unlockFile(l.f)
+	return l.f.Close()
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+// MemoryBackend is a Backend that keeps tasks in memory. It is meant for
+// tests, which can simulate an outside edit with Replace.
+type MemoryBackend struct {
+	mu      sync.Mutex
+	stored  []model.Task // what a Load would return
+	loaded  []model.Task // as of the last Load or Save
+	changed bool         // whether stored was replaced since
+	watches []chan struct{}
+	backups [][]model.Task
+	backup  bool // whether the next Save backs up the stored tasks first
+}
+
+// NewMemoryBackend returns a backend holding a copy of tasks
+func NewMemoryBackend(tasks []model.Task) *MemoryBackend {
+	return &MemoryBackend{stored: cloneTasks(tasks)}
+}
+
+// Path returns a placeholder, as there is no file
+func (b *MemoryBackend) Path() string {
+	return "memory"
+}
+
+// Load returns the stored tasks
+func (b *MemoryBackend) Load() ([]model.Task, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.loaded = cloneTasks(b.stored)
+	b.changed = false
+	return cloneTasks(b.stored), nil
+}
+
+// Loaded returns the tasks as of the last Load or Save
+func (b *MemoryBackend) Loaded() []model.Task {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return cloneTasks(b.loaded)
+}
+
+// Save stores the tasks unless they were replaced since the last Load or Save
+func (b *MemoryBackend) Save(tasks []model.Task) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	backup := b.backup
+	b.backup = false
+	if b.changed {
+		return ErrFileChanged
+	}
+	if backup {
+		b.backups = append(b.backups, cloneTasks(b.stored))
+	}
+	b.stored = cloneTasks(tasks)
+	b.loaded = cloneTasks(tasks)
+	return nil
+}
+
+// Watch returns a channel that is notified whenever Replace is called. The
+// interval is ignored.
+func (b *MemoryBackend) Watch(interval time.Duration) <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	changes := make(chan struct{}, 1)
+	b.watches = append(b.watches, changes)
+	return changes
+}
+
+// Backup records a copy of the stored tasks; see Backups
+func (b *MemoryBackend) Backup(dir string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.backups = append(b.backups, cloneTasks(b.stored))
+	return nil
+}
+
+// backupBeforeSave has the next Save back up the stored tasks, unless it is
+// refused
+func (b *MemoryBackend) backupBeforeSave(dir string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.backup = true
+}
+
+// Replace swaps the stored tasks as if they were edited outside tuiodo
+func (b *MemoryBackend) Replace(tasks []model.Task) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stored = cloneTasks(tasks)
+	b.changed = true
+	for _, changes := range b.watches {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Stored returns a copy of the stored tasks
+func (b *MemoryBackend) Stored() []model.Task {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return cloneTasks(b.stored)
+}
+
+// Backups returns the snapshots taken by Backup, oldest first
+func (b *MemoryBackend) Backups() [][]model.Task {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([][]model.Task(nil), b.backups...)
+}
+
+// cloneTasks copies a task list so callers can't modify the stored one
+func cloneTasks(tasks []model.Task) []model.Task {
+	if tasks == nil {
+		return nil
+	}
+	clones := make([]model.Task, len(tasks))
+	for i, task := range tasks {
+		clones[i] = task.Clone()
+	}
+	return clones
+}
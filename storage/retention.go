@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RetentionPolicy decides which backups are kept, grandfather-father-son
+// style. Every backup younger than KeepAll is kept; older ones are thinned to
+// the newest per hour up to Hourly, per day up to Daily and per week up to
+// Weekly. A Weekly of zero keeps weekly backups forever.
+type RetentionPolicy struct {
+	KeepAll time.Duration
+	Hourly  time.Duration
+	Daily   time.Duration
+	Weekly  time.Duration
+}
+
+// Backup settings beyond those passed to Initialize
+var (
+	retention       *RetentionPolicy // nil keeps the newest maxBackups instead
+	compressBackups bool
+)
+
+// ConfigureBackups sets how backups are pruned and whether they are written
+// gzip-compressed. A nil policy keeps the newest backups up to the maximum
+// count passed to Initialize.
+func ConfigureBackups(policy *RetentionPolicy, compress bool) {
+	retention = policy
+	compressBackups = compress
+}
+
+// cleanupOldBackups removes backups the retention policy doesn't keep, or
+// those exceeding the maximum count if there is no policy. Each file's
+// backups are pruned on their own.
+func cleanupOldBackups() error {
+	if backupDirectory == "" {
+		return nil
+	}
+
+	files, err := listBackupFiles()
+	if err != nil {
+		return err
+	}
+
+	sources := make(map[string][]backupFile)
+	for _, file := range files {
+		sources[file.source] = append(sources[file.source], file)
+	}
+
+	for _, files := range sources {
+		var remove []backupFile
+		if retention != nil {
+			remove = retention.expired(files, time.Now())
+		} else if maxBackups > 0 && len(files) > maxBackups {
+			remove = files[maxBackups:]
+		}
+
+		for _, file := range remove {
+			os.Remove(filepath.Join(backupDirectory, file.name))
+		}
+	}
+	return nil
+}
+
+// expired returns the backups the policy doesn't keep. files must be sorted
+// newest first, so the first backup seen in each period is the one kept.
+func (p RetentionPolicy) expired(files []backupFile, now time.Time) []backupFile {
+	kept := make(map[string]bool)
+	var remove []backupFile
+
+	for _, file := range files {
+		age := now.Sub(file.time)
+
+		var period string
+		switch {
+		case age <= p.KeepAll:
+			continue
+		case age <= p.Hourly:
+			period = "hour " + file.time.Format("2006-01-02 15")
+		case age <= p.Daily:
+			period = "day " + file.time.Format("2006-01-02")
+		case p.Weekly == 0 || age <= p.Weekly:
+			year, week := file.time.ISOWeek()
+			period = fmt.Sprintf("week %d-%02d", year, week)
+		default:
+			remove = append(remove, file)
+			continue
+		}
+
+		if kept[period] {
+			remove = append(remove, file)
+			continue
+		}
+		kept[period] = true
+	}
+
+	return remove
+}
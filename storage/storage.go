@@ -1,11 +1,10 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strings"
 	"sync"
 	"time"
 
@@ -22,6 +21,7 @@ func init() {
 	} else {
 		DefaultTodoFilePath = filepath.Join(homeDir, "TODO.md")
 	}
+	backend = NewMarkdownBackend(DefaultTodoFilePath)
 }
 
 // findGitRepository searches for a git repository starting from the current directory
@@ -47,7 +47,8 @@ func findGitRepository(startPath string) (string, error) {
 	}
 }
 
-// getGitRootTodoPath returns the path to TODO.md at the root of the git repository
+// getGitRootTodoPath returns the path to the project's task file (TODO.md unless
+// configured otherwise) at the root of the git repository
 func getGitRootTodoPath() (string, error) {
 	// Get current working directory
 	currentDir, err := os.Getwd()
@@ -61,32 +62,29 @@ func getGitRootTodoPath() (string, error) {
 		return "", err
 	}
 
-	// Return path to TODO.md at git root
-	return filepath.Join(gitRoot, "TODO.md"), nil
+	// Return path to the task file at git root
+	return filepath.Join(gitRoot, directoryTodoFile), nil
 }
 
 // Storage configuration
 var (
-	todoFilePath    = DefaultTodoFilePath
+	backend         Backend // where tasks are loaded from and saved to
+	explicitFile    bool    // the file was given to Initialize, not found
 	backupDirectory = ""
 	maxBackups      = 5
 	autoSave        = true
 	backupOnSave    = true
-	storageWriteMu  sync.Mutex // mutex to prevent concurrent writes
-)
+	storageWriteMu  sync.Mutex // mutex to prevent concurrent writes within this process
 
-// Regex patterns for parsing metadata - compile only once for better performance
-var (
-	priorityPattern  = regexp.MustCompile(`@priority:(high|medium|low|critical)`)
-	archivedPattern  = regexp.MustCompile(`@archived:(true|false)`)
-	createdAtPattern = regexp.MustCompile(`@created:(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z)`)
-	duePattern       = regexp.MustCompile(`@due:(\d{4}-\d{2}-\d{2})`)
-	tagPattern       = regexp.MustCompile(`@tag:([^\s@]+)`)
-	statusPattern    = regexp.MustCompile(`@status:([^\s@]+)`)
+	backendSwitched = make(chan struct{})               // closed by SetBackend for Watch
+	watches         = make(map[Backend]<-chan struct{}) // see watchBackend
+	watchesMu       sync.Mutex
 )
 
-// Initialize sets up the storage with configurable settings
+// Initialize sets up the storage with configurable settings. Tasks are kept
+// in Markdown at filePath; use SetBackend to switch to another backend.
 func Initialize(filePath string, backupDir string, maxBackupFiles int, enableAutoSave bool, enableBackup bool) {
+	explicitFile = filePath != ""
 	if filePath != "" {
 		// If path is not absolute, make it absolute from current directory
 		if !filepath.IsAbs(filePath) {
@@ -94,22 +92,23 @@ func Initialize(filePath string, backupDir string, maxBackupFiles int, enableAut
 				filePath = absPath
 			}
 		}
-		todoFilePath = filePath
 	} else {
 		// No explicit path provided, try to find git repository first
 		if gitPath, err := getGitRootTodoPath(); err == nil {
-			todoFilePath = gitPath
+			filePath = gitPath
 		} else {
 			// Fall back to current working directory
 			if currentDir, err := os.Getwd(); err == nil {
-				todoFilePath = filepath.Join(currentDir, "TODO.md")
+				filePath = filepath.Join(currentDir, directoryTodoFile)
 			} else {
 				// Last resort: home directory
-				todoFilePath = DefaultTodoFilePath
+				filePath = DefaultTodoFilePath
 			}
 		}
 	}
 
+	backend = NewMarkdownBackend(filePath)
+
 	backupDirectory = backupDir
 
 	if maxBackupFiles > 0 {
@@ -125,9 +124,20 @@ func Initialize(filePath string, backupDir string, maxBackupFiles int, enableAut
 	}
 }
 
+// SetBackend replaces the backend tasks are loaded from and saved to
+func SetBackend(b Backend) {
+	storageWriteMu.Lock()
+	defer storageWriteMu.Unlock()
+	backend = b
+
+	// Let Watch follow the new backend
+	close(backendSwitched)
+	backendSwitched = make(chan struct{})
+}
+
 // GetStoragePath returns the current storage file path
 func GetStoragePath() string {
-	return todoFilePath
+	return backend.Path()
 }
 
 // IsAutoSaveEnabled returns whether auto-save is enabled
@@ -135,219 +145,123 @@ func IsAutoSaveEnabled() bool {
 	return autoSave
 }
 
-// LoadTasks loads tasks from the configured TODO file
+// LoadTasks loads tasks from the configured backend
 func LoadTasks() []model.Task {
-	content, err := os.ReadFile(todoFilePath)
+	tasks, err := backend.Load()
 	if err != nil {
-		// File doesn't exist or can't be read, return empty task list
+		// The tasks can't be read, return empty task list
 		return make([]model.Task, 0)
 	}
-
-	lines := strings.Split(string(content), "\n")
-	tasks := make([]model.Task, 0, len(lines)/3) // Preallocate with estimated capacity
-
-	var currentCategory string
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		// Skip empty lines
-		if line == "" {
-			continue
-		}
-
-		// Check if this is a category header (starts with ##)
-		if strings.HasPrefix(line, "## ") {
-			currentCategory = strings.TrimPrefix(line, "## ")
-			continue
-		}
-
-		// Parse task items (- [ ] or - [x])
-		if strings.HasPrefix(line, "- [") && len(line) > 5 {
-			isDone := line[3] == 'x' || line[3] == 'X'
-			description := strings.TrimSpace(line[5:])
-
-			// Extract priority if present
-			var priority model.Priority
-			priorityMatch := priorityPattern.FindStringSubmatch(description)
-			if len(priorityMatch) > 1 {
-				priorityStr := priorityMatch[1]
-				switch priorityStr {
-				case "critical":
-					priority = model.PriorityCritical
-				case "high":
-					priority = model.PriorityHigh
-				case "medium":
-					priority = model.PriorityMedium
-				case "low":
-					priority = model.PriorityLow
-				default:
-					priority = model.PriorityNone
-				}
-				// Remove the priority tag from description
-				description = strings.TrimSpace(priorityPattern.ReplaceAllString(description, ""))
-			}
-
-			// Extract archived status if present
-			var archived bool
-			archivedMatch := archivedPattern.FindStringSubmatch(description)
-			if len(archivedMatch) > 1 {
-				archivedStr := archivedMatch[1]
-				archived = archivedStr == "true"
-				// Remove the archived tag from description
-				description = strings.TrimSpace(archivedPattern.ReplaceAllString(description, ""))
-			}
-
-			// Extract creation date if present
-			createdAt := time.Now() // Default to now if not found
-			createdAtMatch := createdAtPattern.FindStringSubmatch(description)
-			if len(createdAtMatch) > 1 {
-				if parsedTime, err := time.Parse(time.RFC3339, createdAtMatch[1]); err == nil {
-					createdAt = parsedTime
-				}
-				// Remove the created tag from description
-				description = strings.TrimSpace(createdAtPattern.ReplaceAllString(description, ""))
-			}
-
-			// Extract due date if present
-			var dueDate string
-			dueMatch := duePattern.FindStringSubmatch(description)
-			if len(dueMatch) > 1 {
-				dueDate = dueMatch[1]
-				// Keep the due date in the metadata but remove it from visible description
-				description = strings.TrimSpace(duePattern.ReplaceAllString(description, ""))
-			}
-
-			// Extract tags if present
-			var tags []string
-			tagMatches := tagPattern.FindAllStringSubmatch(description, -1)
-			for _, match := range tagMatches {
-				if len(match) > 1 {
-					tags = append(tags, match[1])
-				}
-			}
-			// Remove the tag markers from visible description
-			description = strings.TrimSpace(tagPattern.ReplaceAllString(description, ""))
-
-			// Extract status if present
-			var status string
-			statusMatch := statusPattern.FindStringSubmatch(description)
-			if len(statusMatch) > 1 {
-				status = statusMatch[1]
-				// Remove the status tag from visible description
-				description = strings.TrimSpace(statusPattern.ReplaceAllString(description, ""))
-			}
-
-			// Create task object with all metadata
-			task := model.Task{
-				Description: description,
-				Done:        isDone,
-				Category:    currentCategory,
-				Priority:    priority,
-				CreatedAt:   createdAt,
-				Archived:    archived, // Use parsed archived status
-				Metadata:    make(map[string]string),
-			}
-
-			// Store additional metadata
-			if dueDate != "" {
-				task.Metadata["due"] = dueDate
-			}
-			if len(tags) > 0 {
-				task.Metadata["tags"] = strings.Join(tags, ",")
-			}
-			if status != "" {
-				task.Metadata["status"] = status
-			}
-
-			tasks = append(tasks, task)
-		}
-	}
-
 	return tasks
 }
 
-// SaveTasks saves tasks to the configured TODO file
+// LoadedTasks returns the tasks as of the last load or save, without reading
+// them again. Together with a fresh LoadTasks it gives the base and the other
+// side for merging outside changes into unsaved ones.
+func LoadedTasks() []model.Task {
+	return backend.Loaded()
+}
+
+// SaveTasks saves tasks to the configured backend, backing up the previous
+// version first if configured, and appends what changed to the activity
+// log. If the stored tasks were changed by something else since they were
+// loaded, nothing is written and ErrFileChanged is returned. A file of a tree
+// changed while the tree's other files are written is the exception: those
+// stay written, and what changed in them is logged.
 func SaveTasks(tasks []model.Task) error {
 	storageWriteMu.Lock()
 	defer storageWriteMu.Unlock()
 
-	// Create backup if configured and the file exists
-	if backupOnSave && backupDirectory != "" {
-		if _, err := os.Stat(todoFilePath); err == nil {
-			createBackup()
+	// Back up the previous version if configured, but only once the backend
+	// knows the save isn't refused
+	backup := backupOnSave && backupDirectory != ""
+	if backup {
+		if b, ok := backend.(deferredBackup); ok {
+			b.backupBeforeSave(backupDirectory)
+		} else {
+			backend.Backup(backupDirectory)
 		}
 	}
 
-	// Build the content with a single StringBuilder for better performance
-	var content strings.Builder
-	// Preallocate some capacity to reduce reallocations
-	content.Grow(len(tasks) * 50)
-
-	// Group tasks by category (using map to avoid n² operations)
-	categorizedTasks := make(map[string][]model.Task)
-	for _, task := range tasks {
-		category := task.Category
-		if category == "" {
-			category = "Uncategorized"
-		}
-		categorizedTasks[category] = append(categorizedTasks[category], task)
+	before := backend.Loaded()
+	if err := backend.Save(tasks); errors.Is(err, ErrFileChanged) {
+		logActivity(model.TaskEvents(before, backend.Loaded(), time.Now()), backend.Path())
+		return err
+	} else if err != nil {
+		return err
+	}
+	if backup {
+		cleanupOldBackups()
 	}
 
-	// Write tasks by category
-	for category, categoryTasks := range categorizedTasks {
-		content.WriteString(fmt.Sprintf("## %s\n\n", category))
-
-		for _, task := range categoryTasks {
-			checkmark := " "
-			if task.Done {
-				checkmark = "x"
-			}
-
-			description := task.Description
+	// The tasks are saved even if the change can't be logged
+	logActivity(model.TaskEvents(before, tasks, time.Now()), backend.Path())
+	return nil
+}
 
-			// Add priority tag if set
-			if task.Priority != "" {
-				description = fmt.Sprintf("%s @priority:%s", description, task.Priority)
-			}
+// Watch reports changes made to the stored tasks outside this process; see
+// Backend.Watch. It follows the current backend when SetBackend switches to
+// another one.
+func Watch(interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
 
-			// Add archived tag if set
-			if task.Archived {
-				description = fmt.Sprintf("%s @archived:true", description)
+	// Start watching the current backend before returning, so no change
+	// made right after is missed
+	storageWriteMu.Lock()
+	current, switched := backend, backendSwitched
+	storageWriteMu.Unlock()
+	backendChanges := watchBackend(current, interval)
+
+	go func() {
+		drain := false
+		for {
+			if drain {
+				// The new backend is loaded after switching, so drop any
+				// change it reported while it wasn't current
+				select {
+				case <-backendChanges:
+				default:
+				}
 			}
 
-			// Add creation date
-			description = fmt.Sprintf("%s @created:%s", description, task.CreatedAt.UTC().Format(time.RFC3339))
-
-			// Add additional metadata if present
-			if dueDate, ok := task.Metadata["due"]; ok {
-				description = fmt.Sprintf("%s @due:%s", description, dueDate)
-			}
-			if tags, ok := task.Metadata["tags"]; ok {
-				for _, tag := range strings.Split(tags, ",") {
-					description = fmt.Sprintf("%s @tag:%s", description, tag)
+			select {
+			case <-backendChanges:
+				select {
+				case changes <- struct{}{}:
+				default:
 				}
-			}
-			if status, ok := task.Metadata["status"]; ok {
-				description = fmt.Sprintf("%s @status:%s", description, status)
+				drain = false
+			case <-switched:
+				drain = true
 			}
 
-			content.WriteString(fmt.Sprintf("- [%s] %s\n", checkmark, description))
+			storageWriteMu.Lock()
+			current, switched = backend, backendSwitched
+			storageWriteMu.Unlock()
+			backendChanges = watchBackend(current, interval)
 		}
-		content.WriteString("\n")
-	}
+	}()
 
-	// Add an invisible tag at the bottom for the tuiodo app
-	// This tag doesn't appear in the TUI but will be visible in raw markdown files
-	content.WriteString("\n<!-- Optimized for [tuiodo](https://github.com/spmfte/tuiodo) -->\n")
+	return changes
+}
 
-	// Ensure the directory exists
-	dir := filepath.Dir(todoFilePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
+// watchBackend returns the channel Backend.Watch returned for b, calling it
+// the first time so switching back and forth doesn't start more watchers
+func watchBackend(b Backend, interval time.Duration) <-chan struct{} {
+	watchesMu.Lock()
+	defer watchesMu.Unlock()
+	if changes, ok := watches[b]; ok {
+		return changes
 	}
+	changes := b.Watch(interval)
+	watches[b] = changes
+	return changes
+}
 
-	return os.WriteFile(todoFilePath, []byte(content.String()), 0644)
+// deferredBackup is a backend that can take the backup before a save itself,
+// once it knows the save isn't refused
+type deferredBackup interface {
+	backupBeforeSave(dir string)
 }
 
 // createBackup creates a backup of the current todo file
@@ -356,23 +270,8 @@ func createBackup() error {
 		return nil
 	}
 
-	// Create backup directory if it doesn't exist
-	if err := os.MkdirAll(backupDirectory, 0755); err != nil {
-		return err
-	}
-
-	// Read existing file
-	data, err := os.ReadFile(todoFilePath)
-	if err != nil {
-		return err
-	}
-
-	// Create backup filename with timestamp
-	timestamp := time.Now().Format("20060102-150405")
-	backupFile := filepath.Join(backupDirectory, fmt.Sprintf("TODO-%s.md", timestamp))
-
-	// Write backup file
-	if err := os.WriteFile(backupFile, data, 0644); err != nil {
+	// Write the snapshot
+	if err := backend.Backup(backupDirectory); err != nil {
 		return err
 	}
 
@@ -383,46 +282,3 @@ func createBackup() error {
 
 	return nil
 }
-
-// cleanupOldBackups removes old backups exceeding the maximum count
-func cleanupOldBackups() error {
-	if backupDirectory == "" || maxBackups <= 0 {
-		return nil
-	}
-
-	// List backup files
-	entries, err := os.ReadDir(backupDirectory)
-	if err != nil {
-		return err
-	}
-
-	// Filter for backup files and sort by modification time
-	backupFiles := make([]string, 0, len(entries))
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasPrefix(entry.Name(), "TODO-") && strings.HasSuffix(entry.Name(), ".md") {
-			backupFiles = append(backupFiles, filepath.Join(backupDirectory, entry.Name()))
-		}
-	}
-
-	// If we have more backups than the limit, delete the oldest ones
-	if len(backupFiles) > maxBackups {
-		// Sort backup files by modification time (oldest first)
-		// We use a simple bubble sort here as the number of files is typically small
-		for i := 0; i < len(backupFiles)-1; i++ {
-			for j := 0; j < len(backupFiles)-i-1; j++ {
-				info1, _ := os.Stat(backupFiles[j])
-				info2, _ := os.Stat(backupFiles[j+1])
-				if info1.ModTime().After(info2.ModTime()) {
-					backupFiles[j], backupFiles[j+1] = backupFiles[j+1], backupFiles[j]
-				}
-			}
-		}
-
-		// Delete the oldest files
-		for i := 0; i < len(backupFiles)-maxBackups; i++ {
-			os.Remove(backupFiles[i])
-		}
-	}
-
-	return nil
-}
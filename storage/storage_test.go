@@ -1,9 +1,14 @@
 package storage
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
 )
 
 func TestFindGitRepository(t *testing.T) {
@@ -228,3 +233,684 @@ func TestDebugGitDetection(t *testing.T) {
 		t.Logf("Could not get git root TODO path: %v", err)
 	}
 }
+
+func TestSaveTasksPreservesSymlinkAndMode(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tuiodo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Real file lives elsewhere and is reached through a symlink
+	realPath := filepath.Join(tempDir, "real", "TODO.md")
+	if err := os.MkdirAll(filepath.Dir(realPath), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(realPath, []byte("## Work\n\n- [ ] Old task\n"), 0600); err != nil {
+		t.Fatalf("Failed to create TODO.md: %v", err)
+	}
+
+	linkPath := filepath.Join(tempDir, "TODO.md")
+	if err := os.Symlink(realPath, linkPath); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+
+	Initialize(linkPath, "", 5, true, false)
+
+	tasks := LoadTasks()
+	tasks = append(tasks, model.Task{Description: "New task", Category: "Work", CreatedAt: time.Now()})
+	if err := SaveTasks(tasks); err != nil {
+		t.Fatalf("SaveTasks failed: %v", err)
+	}
+
+	info, err := os.Lstat(linkPath)
+	if err != nil {
+		t.Fatalf("Failed to stat link: %v", err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected %s to still be a symlink", linkPath)
+	}
+
+	realInfo, err := os.Stat(realPath)
+	if err != nil {
+		t.Fatalf("Failed to stat real file: %v", err)
+	}
+	if realInfo.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 to be preserved, got %o", realInfo.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(realPath)
+	if err != nil {
+		t.Fatalf("Failed to read real file: %v", err)
+	}
+	if !strings.Contains(string(data), "New task") {
+		t.Errorf("Expected new task to be written through the symlink, got:\n%s", data)
+	}
+
+	// No temporary files should be left behind
+	entries, _ := os.ReadDir(filepath.Dir(realPath))
+	if len(entries) != 1 {
+		t.Errorf("Expected only TODO.md in %s, found %d entries", filepath.Dir(realPath), len(entries))
+	}
+}
+
+func TestAcquireLockSerializesWriters(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tuiodo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	todoPath := filepath.Join(tempDir, "TODO.md")
+
+	first, err := acquireLock(todoPath)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		second, err := acquireLock(todoPath)
+		if err == nil {
+			second.release()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Second lock acquired while the first was still held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	first.release()
+
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Second lock was never acquired after release")
+	}
+}
+
+func TestSaveTasksDetectsOutsideChanges(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tuiodo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	todoPath := filepath.Join(tempDir, "TODO.md")
+	if err := os.WriteFile(todoPath, []byte("## Work\n\n- [ ] First task\n"), 0644); err != nil {
+		t.Fatalf("Failed to create TODO.md: %v", err)
+	}
+
+	ConfigureBackups(nil, false)
+	Initialize(todoPath, filepath.Join(tempDir, "backups"), 5, true, true)
+	tasks := LoadTasks()
+	changes := Watch(10 * time.Millisecond)
+
+	// Our own saves are not reported as changes
+	tasks[0].Done = true
+	if err := SaveTasks(tasks); err != nil {
+		t.Fatalf("SaveTasks failed: %v", err)
+	}
+	select {
+	case <-changes:
+		t.Fatal("Own save was reported as an outside change")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Someone else edits the file
+	outside := "## Work\n\n- [ ] First task\n- [ ] Added in an editor\n"
+	if err := os.WriteFile(todoPath, []byte(outside), 0644); err != nil {
+		t.Fatalf("Failed to modify TODO.md: %v", err)
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Outside change was never reported")
+	}
+
+	if err := SaveTasks(tasks); err != ErrFileChanged {
+		t.Fatalf("Expected ErrFileChanged, got %v", err)
+	}
+	data, _ := os.ReadFile(todoPath)
+	if string(data) != outside {
+		t.Errorf("Outside change was overwritten:\n%s", data)
+	}
+	// Nor was a backup taken for the refused save
+	if backups, _ := ListBackups(); len(backups) != 1 {
+		t.Errorf("Expected only the first save's backup, got %d", len(backups))
+	}
+
+	// After reloading, saving works again
+	if tasks = LoadTasks(); len(tasks) != 2 {
+		t.Fatalf("Expected 2 tasks after reload, got %d", len(tasks))
+	}
+	if err := SaveTasks(tasks); err != nil {
+		t.Errorf("SaveTasks after reload failed: %v", err)
+	}
+}
+
+func TestFailedSaveKeepsLoadedTasks(t *testing.T) {
+	tempDir := t.TempDir()
+	todoPath := filepath.Join(tempDir, "TODO.md")
+	if err := os.WriteFile(todoPath, []byte("## Work\n\n- [ ] First task\n"), 0644); err != nil {
+		t.Fatalf("Failed to create TODO.md: %v", err)
+	}
+
+	b := NewMarkdownBackend(todoPath)
+	tasks, err := b.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// A directory in place of the file makes the rename fail, even as root
+	if err := os.Remove(todoPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(todoPath, "blocker"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks[0].Done = true
+	if err := b.Save(tasks); err == nil {
+		t.Fatal("Save over a directory succeeded")
+	}
+	if loaded := b.Loaded(); len(loaded) != 1 || loaded[0].Done {
+		t.Errorf("A failed save changed the loaded tasks: %+v", loaded)
+	}
+}
+
+func TestBackupsAreUniqueAndRestorable(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tuiodo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	todoPath := filepath.Join(tempDir, "TODO.md")
+	backupDir := filepath.Join(tempDir, "backups")
+	original := "## Work\n\n- [ ] First task @id:aaaaaa\n"
+	if err := os.WriteFile(todoPath, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create TODO.md: %v", err)
+	}
+
+	Initialize(todoPath, backupDir, 10, true, true)
+	tasks := LoadTasks()
+
+	// Several saves within the same second each get their own backup
+	for i := 0; i < 3; i++ {
+		tasks[0].Description = fmt.Sprintf("Edit %d", i)
+		if err := SaveTasks(tasks); err != nil {
+			t.Fatalf("SaveTasks failed: %v", err)
+		}
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(backups) != 3 {
+		t.Fatalf("Expected 3 backups, got %d", len(backups))
+	}
+
+	oldest := backups[len(backups)-1]
+	if oldest.Tasks[0].Description != "First task" {
+		t.Fatalf("Expected the oldest backup last, got %+v", backups)
+	}
+
+	restored, err := RestoreBackup(oldest.Name)
+	if err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	if len(restored) != 1 || restored[0].Description != "First task" {
+		t.Errorf("Unexpected restored tasks: %+v", restored)
+	}
+	if data, _ := os.ReadFile(todoPath); string(data) != original {
+		t.Errorf("Expected the original file back, got:\n%s", data)
+	}
+
+	// The state before the restore was backed up
+	backups, _ = ListBackups()
+	if len(backups) != 4 || backups[0].Tasks[0].Description != "Edit 2" {
+		t.Errorf("Expected a safety backup of the last edit, got %+v", backups)
+	}
+}
+
+func TestRetentionPolicyThinsOlderBackups(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	policy := RetentionPolicy{
+		KeepAll: time.Hour,
+		Hourly:  24 * time.Hour,
+		Daily:   30 * 24 * time.Hour,
+	}
+
+	ages := []time.Duration{
+		10 * time.Minute, 20 * time.Minute, // Within the hour: both kept
+		2*time.Hour + 10*time.Minute, 2*time.Hour + 20*time.Minute, // Same hour: newest kept
+		3 * 24 * time.Hour, 3*24*time.Hour + time.Minute, // Same day: newest kept
+		60 * 24 * time.Hour, 61 * 24 * time.Hour, // Same ISO week: newest kept forever
+	}
+	var files []backupFile
+	for i, age := range ages {
+		files = append(files, backupFile{name: fmt.Sprintf("b%d", i), time: now.Add(-age)})
+	}
+
+	var removed []string
+	for _, file := range policy.expired(files, now) {
+		removed = append(removed, file.name)
+	}
+
+	expected := []string{"b3", "b5", "b7"}
+	if strings.Join(removed, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v removed, got %v", expected, removed)
+	}
+
+	policy.Weekly = 7 * 24 * time.Hour
+	if removed := policy.expired(files, now); len(removed) != 4 {
+		t.Errorf("Expected backups older than the weekly tier removed, got %d removed", len(removed))
+	}
+}
+
+func TestCompressedBackupsAreListed(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tuiodo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	todoPath := filepath.Join(tempDir, "TODO.md")
+	backupDir := filepath.Join(tempDir, "backups")
+	if err := os.WriteFile(todoPath, []byte("- [ ] First task @id:aaaaaa\n"), 0644); err != nil {
+		t.Fatalf("Failed to create TODO.md: %v", err)
+	}
+
+	Initialize(todoPath, backupDir, 10, true, true)
+	ConfigureBackups(nil, true)
+	defer ConfigureBackups(nil, false)
+
+	tasks := LoadTasks()
+	tasks[0].Done = true
+	if err := SaveTasks(tasks); err != nil {
+		t.Fatalf("SaveTasks failed: %v", err)
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(backups) != 1 || !strings.HasSuffix(backups[0].Name, ".md.gz") {
+		t.Fatalf("Expected one compressed backup, got %+v", backups)
+	}
+	if backups[0].Tasks[0].Description != "First task" || backups[0].Tasks[0].Done {
+		t.Errorf("Expected the backup to hold the task before the save, got %+v", backups[0].Tasks)
+	}
+
+	if _, err := RestoreBackup(backups[0].Name); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	if LoadTasks()[0].Done {
+		t.Error("Expected the restored task to be pending")
+	}
+}
+
+func TestFilesKeepTheirOwnBackups(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tuiodo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	projectPath := filepath.Join(tempDir, "project", "TODO.md")
+	globalPath := filepath.Join(tempDir, "TODO.md")
+	backupDir := filepath.Join(tempDir, "backups")
+	os.MkdirAll(filepath.Dir(projectPath), 0755)
+	if err := os.WriteFile(projectPath, []byte("- [ ] Ship it @id:aaaaaa\n"), 0644); err != nil {
+		t.Fatalf("Failed to create project TODO.md: %v", err)
+	}
+	if err := os.WriteFile(globalPath, []byte("- [ ] Call the bank @id:cccccc\n"), 0644); err != nil {
+		t.Fatalf("Failed to create global TODO.md: %v", err)
+	}
+
+	ConfigureFiles(globalPath, "")
+	defer ConfigureFiles(DefaultTodoFilePath, "")
+	ConfigureBackups(nil, false)
+	Initialize(projectPath, backupDir, 2, true, true)
+	if err := OpenWorkspace(BackendMarkdown, FileProject); err != nil {
+		t.Fatalf("OpenWorkspace failed: %v", err)
+	}
+
+	// Both files are saved more often than the count kept
+	for _, name := range []string{FileProject, FileGlobal} {
+		if err := SwitchFile(name); err != nil {
+			t.Fatalf("SwitchFile failed: %v", err)
+		}
+		tasks := LoadTasks()
+		for i := 0; i < 3; i++ {
+			tasks[0].Description = fmt.Sprintf("Edit %d", i)
+			if err := SaveTasks(tasks); err != nil {
+				t.Fatalf("SaveTasks failed: %v", err)
+			}
+		}
+	}
+
+	// The global file's saves didn't prune the project file's backups, and
+	// each file only lists its own
+	globalBackups, err := ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(globalBackups) != 2 || globalBackups[1].Tasks[0].Description != "Edit 0" {
+		t.Fatalf("Expected the global file's two newest backups, got %+v", globalBackups)
+	}
+	if err := SwitchFile(FileProject); err != nil {
+		t.Fatalf("SwitchFile failed: %v", err)
+	}
+	projectBackups, err := ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(projectBackups) != 2 || projectBackups[1].Tasks[0].Description != "Edit 0" {
+		t.Fatalf("Expected the project file's two newest backups, got %+v", projectBackups)
+	}
+	for _, backup := range projectBackups {
+		if backup.Tasks[0].ID != "aaaaaa" {
+			t.Errorf("Expected only project backups, got %+v", backup.Tasks)
+		}
+	}
+
+	// A backup of the global file can't be restored over the project file
+	if _, err := RestoreBackup(globalBackups[0].Name); err == nil {
+		t.Error("Expected restoring another file's backup to fail")
+	}
+	if tasks := LoadTasks(); tasks[0].Description != "Edit 2" {
+		t.Errorf("Expected the project tasks to be unchanged, got %+v", tasks)
+	}
+}
+
+func TestWorkspaceSwitchAndMove(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tuiodo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	if resolved, err := filepath.EvalSymlinks(tempDir); err == nil {
+		tempDir = resolved
+	}
+
+	projectPath := filepath.Join(tempDir, "project", "TODO.md")
+	globalPath := filepath.Join(tempDir, "TODO.md")
+	os.MkdirAll(filepath.Dir(projectPath), 0755)
+	project := "## Work\n\n- [ ] Ship it @id:aaaaaa\n  - [ ] Write notes @id:bbbbbb\n"
+	if err := os.WriteFile(projectPath, []byte(project), 0644); err != nil {
+		t.Fatalf("Failed to create project TODO.md: %v", err)
+	}
+	if err := os.WriteFile(globalPath, []byte("- [ ] Call the bank @id:cccccc\n"), 0644); err != nil {
+		t.Fatalf("Failed to create global TODO.md: %v", err)
+	}
+
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(filepath.Dir(projectPath)); err != nil {
+		t.Fatalf("Failed to change to project directory: %v", err)
+	}
+
+	ConfigureFiles(globalPath, "")
+	defer ConfigureFiles(DefaultTodoFilePath, "")
+	Initialize("", "", 5, true, false)
+	if err := OpenWorkspace(BackendMarkdown, FileGlobal); err != nil {
+		t.Fatalf("OpenWorkspace failed: %v", err)
+	}
+
+	// The remembered file is opened
+	if ActiveFile().Name != FileGlobal || GetStoragePath() != globalPath {
+		t.Fatalf("Expected the global file to be active, got %s at %s", ActiveFile().Name, GetStoragePath())
+	}
+	if next, ok := NextFile(); !ok || next.Name != FileProject {
+		t.Fatalf("Expected the project file next, got %+v", next)
+	}
+
+	if err := SwitchFile(FileProject); err != nil {
+		t.Fatalf("SwitchFile failed: %v", err)
+	}
+	tasks := LoadTasks()
+	if len(tasks) != 2 || GetStoragePath() != projectPath {
+		t.Fatalf("Expected the project tasks, got %+v from %s", tasks, GetStoragePath())
+	}
+
+	// Moving a task takes its subtasks along
+	tasks[0].ParentID = ""
+	if err := MoveTasks(tasks, FileGlobal); err != nil {
+		t.Fatalf("MoveTasks failed: %v", err)
+	}
+	data, err := os.ReadFile(globalPath)
+	if err != nil {
+		t.Fatalf("Failed to read global TODO.md: %v", err)
+	}
+	for _, want := range []string{"- [ ] Call the bank", "- [ ] Ship it", "  - [ ] Write notes"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected global file to contain %q, got:\n%s", want, data)
+		}
+	}
+
+	// A project file that is the global file makes a workspace of one
+	ConfigureFiles(projectPath, "")
+	if err := OpenWorkspace(BackendMarkdown, FileGlobal); err != nil {
+		t.Fatalf("OpenWorkspace failed: %v", err)
+	}
+	if _, ok := NextFile(); ok || ActiveFile().Name != FileProject {
+		t.Errorf("Expected a single project file, got %+v", WorkspaceFiles())
+	}
+}
+
+func TestExplicitFileOverridesActiveFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tuiodo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	projectPath := filepath.Join(tempDir, "project", "TODO.md")
+	globalPath := filepath.Join(tempDir, "TODO.md")
+	os.MkdirAll(filepath.Dir(projectPath), 0755)
+	if err := os.WriteFile(projectPath, []byte("- [ ] Ship it @id:aaaaaa\n"), 0644); err != nil {
+		t.Fatalf("Failed to create project TODO.md: %v", err)
+	}
+	if err := os.WriteFile(globalPath, []byte("- [ ] Call the bank @id:cccccc\n"), 0644); err != nil {
+		t.Fatalf("Failed to create global TODO.md: %v", err)
+	}
+
+	// A file given with --storage wins over the one remembered as active
+	ConfigureFiles(globalPath, "")
+	defer ConfigureFiles(DefaultTodoFilePath, "")
+	Initialize(projectPath, "", 5, true, false)
+	if err := OpenWorkspace(BackendMarkdown, FileGlobal); err != nil {
+		t.Fatalf("OpenWorkspace failed: %v", err)
+	}
+	if ActiveFile().Name != FileProject || GetStoragePath() != projectPath {
+		t.Fatalf("Expected the explicit file to be active, got %s at %s", ActiveFile().Name, GetStoragePath())
+	}
+	tasks := LoadTasks()
+	if len(tasks) != 1 || tasks[0].Description != "Ship it" {
+		t.Errorf("Expected the tasks of the explicit file, got %+v", tasks)
+	}
+
+	// The global file can still be switched to
+	if next, ok := NextFile(); !ok || next.Name != FileGlobal {
+		t.Errorf("Expected the global file next, got %+v", next)
+	}
+}
+
+func TestTreeWritesTasksBackToTheirFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tuiodo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"TODO.md":                   "- [ ] Release notes\n",
+		"pkg/api/TODO.md":           "- [ ] Write tests\n",
+		"pkg/cli/TODO.md":           "## CLI\n\n- [ ] Write tests\n",
+		"node_modules/dep/TODO.md":  "- [ ] Not ours\n",
+		"pkg/cli/testdata/NOTES.md": "- [ ] Not a task file\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	Initialize(filepath.Join(tempDir, "TODO.md"), "", 5, true, false)
+	if err := OpenTree(tempDir, []string{".git", "node_modules"}); err != nil {
+		t.Fatalf("OpenTree failed: %v", err)
+	}
+	defer Initialize(DefaultTodoFilePath, "", 5, true, false)
+
+	tasks := LoadTasks()
+	if len(tasks) != 3 {
+		t.Fatalf("Expected 3 tasks from 3 files, got %+v", tasks)
+	}
+	sources := []string{tasks[0].Source, tasks[1].Source, tasks[2].Source}
+	if strings.Join(sources, ",") != ".,pkg/api,pkg/cli" {
+		t.Errorf("Expected sources in tree order, got %v", sources)
+	}
+	// The same line in two files gets two IDs
+	if tasks[1].ID == tasks[2].ID {
+		t.Errorf("Expected unique IDs across files, both are %s", tasks[1].ID)
+	}
+
+	// Completing the CLI task writes only the CLI file
+	tasks[2].Done = true
+	if err := SaveTasks(tasks); err != nil {
+		t.Fatalf("SaveTasks failed: %v", err)
+	}
+	for name, content := range files {
+		data, _ := os.ReadFile(filepath.Join(tempDir, name))
+		if name == "pkg/cli/TODO.md" {
+			if !strings.HasPrefix(string(data), "## CLI\n\n- [x] Write tests") {
+				t.Errorf("Expected the CLI task to be completed in its file, got:\n%s", data)
+			}
+		} else if string(data) != content {
+			t.Errorf("Expected %s to be left alone, got:\n%s", name, data)
+		}
+	}
+
+	// The renamed ID still matches the task on the next load
+	if reloaded := LoadTasks(); reloaded[2].ID != tasks[2].ID || !reloaded[2].Done {
+		t.Errorf("Expected the completed CLI task after reloading, got %+v", reloaded[2])
+	}
+
+	// A file changed outside keeps the other files from being written too
+	tasks = LoadTasks()
+	apiPath := filepath.Join(tempDir, "pkg/api/TODO.md")
+	if err := os.WriteFile(apiPath, []byte("- [ ] Write more tests\n"), 0644); err != nil {
+		t.Fatalf("Failed to change %s: %v", apiPath, err)
+	}
+	tasks[0].Done = true
+	tasks[1].Done = true
+	if err := SaveTasks(tasks); err != ErrFileChanged {
+		t.Fatalf("Expected ErrFileChanged, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tempDir, "TODO.md")); string(data) != files["TODO.md"] {
+		t.Errorf("Expected the root file to be left alone, got:\n%s", data)
+	}
+}
+
+func TestActivityLogRecordsChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	todoPath := filepath.Join(tempDir, "TODO.md")
+	content := "## Work\n\n- [ ] Write report @id:aaaaaa\n- [ ] Call Bob @id:bbbbbb\n"
+	if err := os.WriteFile(todoPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create TODO.md: %v", err)
+	}
+
+	Initialize(todoPath, filepath.Join(tempDir, "backups"), 10, true, false)
+	defer Initialize(DefaultTodoFilePath, "", 5, true, false)
+	tasks := LoadTasks()
+
+	tasks[0].SetDone(true)
+	tasks[1].Priority = model.PriorityHigh
+	tasks = append(tasks, model.Task{ID: "cccccc", Description: "New task", Category: "Home"})
+	if err := SaveTasks(tasks); err != nil {
+		t.Fatalf("SaveTasks failed: %v", err)
+	}
+	if err := SaveTasks(tasks[1:]); err != nil {
+		t.Fatalf("SaveTasks failed: %v", err)
+	}
+
+	if got := ActivityLogPath(); got != filepath.Join(tempDir, "activity.jsonl") {
+		t.Errorf("Activity log at %s, want it next to the backup directory", got)
+	}
+
+	actions := func(filter ActivityFilter) string {
+		events, err := ReadActivity(filter)
+		if err != nil {
+			t.Fatalf("ReadActivity failed: %v", err)
+		}
+		var actions []string
+		for _, event := range events {
+			actions = append(actions, event.TaskID+":"+event.Action)
+		}
+		return strings.Join(actions, ",")
+	}
+
+	if got := actions(ActivityFilter{TaskID: "aaaaaa"}); got != "aaaaaa:completed,aaaaaa:deleted" {
+		t.Errorf("History of aaaaaa: %s", got)
+	}
+	if got := actions(ActivityFilter{Category: "home"}); got != "cccccc:created" {
+		t.Errorf("Changes in Home: %s", got)
+	}
+	if got := actions(ActivityFilter{TaskID: "bbbbbb", Since: time.Now().Add(time.Hour)}); got != "" {
+		t.Errorf("Changes in the future: %s", got)
+	}
+	if got := actions(ActivityFilter{TaskID: "bbbbbb"}); got != "bbbbbb:prioritised" {
+		t.Errorf("History of bbbbbb: %s", got)
+	}
+}
+
+func TestRestoreBackupIsLogged(t *testing.T) {
+	tempDir := t.TempDir()
+	todoPath := filepath.Join(tempDir, "TODO.md")
+	content := "## Work\n\n- [ ] Write report @id:aaaaaa\n"
+	if err := os.WriteFile(todoPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create TODO.md: %v", err)
+	}
+
+	ConfigureBackups(nil, false)
+	Initialize(todoPath, filepath.Join(tempDir, "backups"), 10, true, true)
+	defer Initialize(DefaultTodoFilePath, "", 5, true, false)
+	tasks := LoadTasks()
+
+	tasks[0].SetDone(true)
+	if err := SaveTasks(tasks); err != nil {
+		t.Fatalf("SaveTasks failed: %v", err)
+	}
+	backups, err := ListBackups()
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("Expected one backup, got %+v (%v)", backups, err)
+	}
+	if _, err := RestoreBackup(backups[0].Name); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+
+	// Restoring the backup reopened the task
+	events, err := ReadActivity(ActivityFilter{TaskID: "aaaaaa"})
+	if err != nil {
+		t.Fatalf("ReadActivity failed: %v", err)
+	}
+	var actions []string
+	for _, event := range events {
+		actions = append(actions, event.Action)
+	}
+	if got := strings.Join(actions, ","); got != "completed,reopened" {
+		t.Errorf("History of aaaaaa: %s", got)
+	}
+}
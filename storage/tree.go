@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+// errTreeBackups is returned by the backup functions for a tree, whose
+// backups are of single files and can't stand in for all of its tasks
+var errTreeBackups = errors.New("backups can't be browsed or restored with --tree; run tuiodo in the file's directory instead")
+
+// treeFile is one task file below the root of a tree
+type treeFile struct {
+	source  string // directory of the file relative to the root, "." for the root
+	backend Backend
+	ids     map[string]string // IDs renamed to be unique in the tree, to the ID in the file
+}
+
+// treeBackend is a Backend combining every task file below a directory. Each
+// task's Source says which file it came from, and saving writes it back
+// there. Tasks without a source go to the file at the root.
+type treeBackend struct {
+	root  string
+	files []*treeFile // the root's file first
+
+	mu        sync.Mutex // guards the ID maps in files and backupDir
+	backupDir string     // where the next Save backs up the files it writes
+}
+
+// FindTaskFiles walks the directory tree below root and returns the path of
+// every file with the given name, skipping directories named in exclude. The
+// paths are in lexical order, so a directory's file comes before those of
+// its subdirectories.
+func FindTaskFiles(root, name string, exclude []string) ([]string, error) {
+	skip := make(map[string]bool, len(exclude))
+	for _, dir := range exclude {
+		skip[dir] = true
+	}
+
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // Skip what can't be read rather than giving up
+		}
+		if entry.IsDir() {
+			if path != root && skip[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Name() == name {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// OpenTree makes every task file below root the current backend, found as
+// by FindTaskFiles with the configured project file name. The file at the
+// root is included even if it doesn't exist yet, to hold tasks added there.
+func OpenTree(root string, exclude []string) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	paths, err := FindTaskFiles(root, directoryTodoFile, exclude)
+	if err != nil {
+		return err
+	}
+
+	rootFile := filepath.Join(root, directoryTodoFile)
+	if len(paths) == 0 || paths[0] != rootFile {
+		paths = append([]string{rootFile}, paths...)
+	}
+
+	tree := &treeBackend{root: root}
+	for _, path := range paths {
+		source, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		var b Backend
+		if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+			b = NewJSONLBackend(path)
+		} else {
+			b = NewMarkdownBackend(path)
+		}
+		tree.files = append(tree.files, &treeFile{source: filepath.ToSlash(source), backend: b})
+	}
+
+	workspace = []WorkspaceFile{{Name: FileTree, backend: tree}}
+	activeFile = 0
+	SetBackend(tree)
+	return nil
+}
+
+// Path returns the root of the tree
+func (b *treeBackend) Path() string {
+	return b.root
+}
+
+// Load reads every file in the tree
+func (b *treeBackend) Load() ([]model.Task, error) {
+	lists := make([][]model.Task, len(b.files))
+	for i, file := range b.files {
+		tasks, err := file.backend.Load()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.backend.Path(), err)
+		}
+		lists[i] = tasks
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tasks, ids := b.combine(lists)
+	b.setIDs(ids)
+	return tasks, nil
+}
+
+// Loaded returns the tasks of every file as of the last load or save
+func (b *treeBackend) Loaded() []model.Task {
+	lists := make([][]model.Task, len(b.files))
+	for i, file := range b.files {
+		lists[i] = file.backend.Loaded()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tasks, _ := b.combine(lists)
+	return tasks
+}
+
+// Save writes each task back to the file it came from. Files whose tasks
+// didn't change aren't written. If any file to be written was changed by
+// something else, none are written and ErrFileChanged is returned.
+func (b *treeBackend) Save(tasks []model.Task) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bySource := make(map[string]int, len(b.files))
+	for i, file := range b.files {
+		bySource[file.source] = i
+	}
+	lists := make([][]model.Task, len(b.files))
+	for _, task := range tasks {
+		i := bySource[task.Source] // The root's file if the source is unknown
+		file := b.files[i]
+
+		task = task.Clone()
+		task.Source = file.source
+		if id, ok := file.ids[task.ID]; ok {
+			task.ID = id
+		}
+		if id, ok := file.ids[task.ParentID]; ok {
+			task.ParentID = id
+		}
+		lists[i] = append(lists[i], task)
+	}
+
+	backupDir := b.backupDir
+	b.backupDir = ""
+
+	// Check every file before writing any, so a refused save writes nothing
+	write := make([]bool, len(b.files))
+	for i, file := range b.files {
+		write[i] = !sameTasks(lists[i], file.backend.Loaded())
+		if write[i] && changedOutside(file.backend) {
+			return ErrFileChanged
+		}
+	}
+
+	var saveErr error
+	for i, file := range b.files {
+		if !write[i] {
+			continue
+		}
+		if backupDir != "" {
+			if err := file.backend.Backup(backupDir); err != nil {
+				return fmt.Errorf("%s: %w", file.backend.Path(), err)
+			}
+		}
+		err := file.backend.Save(lists[i])
+		if errors.Is(err, ErrFileChanged) {
+			saveErr = err
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", file.backend.Path(), err)
+		}
+	}
+
+	saved := make([][]model.Task, len(b.files))
+	for i, file := range b.files {
+		saved[i] = file.backend.Loaded()
+	}
+	_, ids := b.combine(saved)
+	b.setIDs(ids)
+	return saveErr
+}
+
+// Watch reports changes to any of the files in the tree
+func (b *treeBackend) Watch(interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	for _, file := range b.files {
+		go func(fileChanges <-chan struct{}) {
+			for range fileChanges {
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}(file.backend.Watch(interval))
+	}
+	return changes
+}
+
+// Backup has the next Save back up the files it writes into dir, rather
+// than every file in the tree
+func (b *treeBackend) Backup(dir string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.backupDir = dir
+	return nil
+}
+
+// backupBeforeSave is the same as Backup, which already waits for the next
+// Save
+func (b *treeBackend) backupBeforeSave(dir string) {
+	b.Backup(dir)
+}
+
+// combine lists the tasks of every file, one list per file in b.files, as
+// a single list with their Source set. IDs already used by an earlier file
+// are renamed; the returned maps, one per file, lead back to the originals.
+func (b *treeBackend) combine(lists [][]model.Task) ([]model.Task, []map[string]string) {
+	var tasks []model.Task
+	used := make(map[string]bool)
+	ids := make([]map[string]string, len(b.files))
+
+	for i, file := range b.files {
+		renamed := make(map[string]string)
+		for _, task := range lists[i] {
+			if !used[task.ID] {
+				used[task.ID] = true
+				continue
+			}
+			// The same ID again, e.g. a line copied from another package;
+			// derive one from where it is so it stays the same across loads
+			id := model.DeriveTaskID(file.source + "/" + task.ID)
+			for used[id] {
+				id = model.DeriveTaskID(file.source + "/" + id)
+			}
+			used[id] = true
+			renamed[task.ID] = id
+		}
+
+		ids[i] = make(map[string]string, len(renamed))
+		for original, id := range renamed {
+			ids[i][id] = original
+		}
+
+		for _, task := range lists[i] {
+			task = task.Clone()
+			task.Source = file.source
+			if id, ok := renamed[task.ID]; ok {
+				task.ID = id
+			}
+			if id, ok := renamed[task.ParentID]; ok {
+				task.ParentID = id
+			}
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, ids
+}
+
+// setIDs keeps the ID maps returned by combine for the next save
+func (b *treeBackend) setIDs(ids []map[string]string) {
+	for i, file := range b.files {
+		file.ids = ids[i]
+	}
+}
+
+// sameTasks reports whether two lists hold the same tasks, in any order
+func sameTasks(a, b []model.Task) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	byID := make(map[string]model.Task, len(b))
+	for _, task := range b {
+		byID[task.ID] = task
+	}
+	for _, task := range a {
+		other, ok := byID[task.ID]
+		if !ok || !model.SameTask(task, other) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spmfte/tuiodo/model"
+)
+
+// Names of the files in the workspace
+const (
+	FileProject = "project" // The task file of the current project
+	FileGlobal  = "global"  // The task file shared by every project
+	FileTree    = "tree"    // Every task file below a directory; see OpenTree
+)
+
+// WorkspaceFile is one of the task files tuiodo can switch between
+type WorkspaceFile struct {
+	Name    string
+	backend Backend
+}
+
+// Path returns where the file's tasks are stored
+func (f WorkspaceFile) Path() string {
+	return f.backend.Path()
+}
+
+// Workspace configuration
+var (
+	globalTodoFile    = ""        // the global task file; DefaultTodoFilePath if empty
+	directoryTodoFile = "TODO.md" // the name of a project's task file
+	workspace         []WorkspaceFile
+	activeFile        int
+)
+
+// ConfigureFiles sets the global task file and the name of the task file
+// looked for in a project. Call it before Initialize. Empty values keep the
+// defaults.
+func ConfigureFiles(globalFile, directoryFile string) {
+	if globalFile != "" {
+		globalTodoFile = globalFile
+	}
+	if directoryFile != "" {
+		directoryTodoFile = directoryFile
+	}
+}
+
+// OpenWorkspace sets up the project file found by Initialize and the global
+// file, both stored with the named backend, and makes the one named active
+// the current backend. A file passed to Initialize explicitly is always
+// opened as the project file, whichever file was active before. If the
+// project file is the global file there is only one file in the workspace.
+func OpenWorkspace(format, active string) error {
+	if explicitFile {
+		active = FileProject
+	}
+	project, err := NewBackend(format, GetStoragePath())
+	if err != nil {
+		return err
+	}
+	workspace = []WorkspaceFile{{Name: FileProject, backend: project}}
+
+	globalPath := globalTodoFile
+	if globalPath == "" {
+		globalPath = DefaultTodoFilePath
+	}
+	global, err := NewBackend(format, globalPath)
+	if err != nil {
+		return err
+	}
+	if !samePath(global.Path(), project.Path()) {
+		workspace = append(workspace, WorkspaceFile{Name: FileGlobal, backend: global})
+	}
+
+	activeFile = 0
+	for i, file := range workspace {
+		if file.Name == active {
+			activeFile = i
+		}
+	}
+	SetBackend(workspace[activeFile].backend)
+	return nil
+}
+
+// WorkspaceFiles returns the files in the workspace, the project file first
+func WorkspaceFiles() []WorkspaceFile {
+	return append([]WorkspaceFile(nil), workspace...)
+}
+
+// ActiveFile returns the file tasks are currently loaded from and saved to.
+// Before OpenWorkspace it is the file set up by Initialize.
+func ActiveFile() WorkspaceFile {
+	if len(workspace) == 0 {
+		return WorkspaceFile{Name: FileProject, backend: backend}
+	}
+	return workspace[activeFile]
+}
+
+// NextFile returns the file after the active one, wrapping around. ok is
+// false if the workspace has only one file.
+func NextFile() (file WorkspaceFile, ok bool) {
+	if len(workspace) < 2 {
+		return WorkspaceFile{}, false
+	}
+	return workspace[(activeFile+1)%len(workspace)], true
+}
+
+// SwitchFile makes the named file the current backend. Load the tasks again
+// afterwards.
+func SwitchFile(name string) error {
+	idx, err := workspaceIndex(name)
+	if err != nil {
+		return err
+	}
+	activeFile = idx
+	SetBackend(workspace[idx].backend)
+	return nil
+}
+
+// MoveTasks adds tasks to the end of the named file and saves it. The caller
+// removes them from the active file. Tasks whose ID is already used in the
+// other file get a new one, and their subtasks follow.
+func MoveTasks(tasks []model.Task, to string) error {
+	idx, err := workspaceIndex(to)
+	if err != nil {
+		return err
+	}
+	target := workspace[idx].backend
+
+	storageWriteMu.Lock()
+	defer storageWriteMu.Unlock()
+
+	existing, err := target.Load()
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]bool, len(existing))
+	for _, task := range existing {
+		used[task.ID] = true
+	}
+	renamed := make(map[string]string)
+	for _, task := range tasks {
+		if used[task.ID] {
+			id := model.NewTaskID()
+			for used[id] {
+				id = model.NewTaskID()
+			}
+			renamed[task.ID] = id
+			used[id] = true
+		}
+	}
+
+	moved := make([]model.Task, len(tasks))
+	for i, task := range tasks {
+		moved[i] = task.Clone()
+		if id, ok := renamed[task.ID]; ok {
+			moved[i].ID = id
+		}
+		if id, ok := renamed[task.ParentID]; ok {
+			moved[i].ParentID = id
+		}
+	}
+
+	if backupOnSave && backupDirectory != "" {
+		if err := target.Backup(backupDirectory); err != nil {
+			return err
+		}
+	}
+	all := append(existing, moved...)
+	if err := target.Save(all); err != nil {
+		return err
+	}
+	logActivity(model.TaskEvents(existing, all, time.Now()), target.Path())
+	return nil
+}
+
+// workspaceIndex returns the index of the named file in the workspace
+func workspaceIndex(name string) (int, error) {
+	for i, file := range workspace {
+		if file.Name == name {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("no %s task file in the workspace", name)
+}
+
+// samePath reports whether two paths name the same file
+func samePath(a, b string) bool {
+	if absA, err := filepath.Abs(a); err == nil {
+		a = absA
+	}
+	if absB, err := filepath.Abs(b); err == nil {
+		b = absB
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
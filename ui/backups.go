@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spmfte/tuiodo/model"
+	"github.com/spmfte/tuiodo/storage"
+)
+
+// renderBackupScreen shows the backup browser: the list of backups, or the
+// tasks of the selected one compared with the current tasks
+func renderBackupScreen(m model.Model, styles map[string]lipgloss.Style, width int) string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(styles["title"].GetForeground()).
+		Padding(0, 1).
+		MarginBottom(1)
+
+	keyStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(styles["helpCommand"].GetForeground())
+
+	// Leave room for the title, summary, key hints and borders
+	rows := m.Height - 12
+	if rows < 5 {
+		rows = 5
+	}
+
+	var content []string
+	backup := m.Backups[m.BackupCursor]
+
+	if !m.BackupOpen {
+		content = append(content,
+			titleStyle.Render("BACKUPS"),
+			styles["inputHint"].Render(storage.GetBackupDirectory()),
+			"",
+		)
+
+		start, end := scrollWindow(m.BackupCursor, len(m.Backups), rows)
+		for i := start; i < end; i++ {
+			b := m.Backups[i]
+			done := 0
+			for _, task := range b.Tasks {
+				if task.Done {
+					done++
+				}
+			}
+
+			line := fmt.Sprintf("%s  %-28s %3d tasks (%d done)  %s",
+				b.Time.Format("2006-01-02 15:04:05"), b.Name, len(b.Tasks), done,
+				renderDiffSummary(model.DiffTasks(b.Tasks, m.Tasks), styles))
+
+			if i == m.BackupCursor {
+				content = append(content, styles["cursor"].Render("→ ")+line)
+			} else {
+				content = append(content, "  "+line)
+			}
+		}
+
+		content = append(content, "",
+			fmt.Sprintf("%s : Show tasks    %s : Restore    %s : Close",
+				keyStyle.Render("enter"), keyStyle.Render("r"), keyStyle.Render("esc")))
+	} else {
+		diff := model.DiffTasks(backup.Tasks, m.Tasks)
+		content = append(content,
+			titleStyle.Render(backup.Name),
+			"Since this backup: "+renderDiffSummary(diff, styles),
+			"",
+		)
+
+		start, end := scrollWindow(m.BackupTaskIdx, len(backup.Tasks), rows)
+		for i := start; i < end; i++ {
+			task := backup.Tasks[i]
+
+			checkbox := "[ ]"
+			if task.Done {
+				checkbox = "[✓]"
+			}
+			line := fmt.Sprintf("%s %s", checkbox, task.Description)
+			if task.Category != "" {
+				line = fmt.Sprintf("%s %s", line, styles["inputHint"].Render("("+task.Category+")"))
+			}
+
+			switch model.BackupTaskStatus(task, m.Tasks) {
+			case "removed":
+				line += " " + styles["priorityHigh"].Render("removed since")
+			case "completed":
+				line += " " + styles["priorityLow"].Render("completed since")
+			case "reopened":
+				line += " " + styles["priorityMedium"].Render("reopened since")
+			case "changed":
+				line += " " + styles["priorityMedium"].Render("changed since")
+			}
+
+			if i == m.BackupTaskIdx {
+				content = append(content, styles["cursor"].Render("→ ")+line)
+			} else {
+				content = append(content, "  "+line)
+			}
+		}
+
+		for _, task := range diff.Added {
+			content = append(content, styles["inputHint"].Render("  + "+task.Description+" (added since)"))
+		}
+
+		content = append(content, "",
+			fmt.Sprintf("%s : Restore this task    %s : Restore whole backup    %s : Back",
+				keyStyle.Render("p"), keyStyle.Render("r"), keyStyle.Render("esc")))
+	}
+
+	if m.StatusMessage != "" {
+		content = append(content, "", styles["secondary"].Render(m.StatusMessage))
+	}
+
+	return styles["helpBox"].Copy().Width(width).Render(strings.Join(content, "\n"))
+}
+
+// renderDiffSummary shortens a diff to counts such as "+2 -1 ✓3"
+func renderDiffSummary(diff model.TaskDiff, styles map[string]lipgloss.Style) string {
+	if diff.Empty() {
+		return styles["inputHint"].Render("no changes")
+	}
+
+	var parts []string
+	if n := len(diff.Added); n > 0 {
+		parts = append(parts, styles["priorityLow"].Render(fmt.Sprintf("+%d added", n)))
+	}
+	if n := len(diff.Removed); n > 0 {
+		parts = append(parts, styles["priorityHigh"].Render(fmt.Sprintf("-%d removed", n)))
+	}
+	if n := len(diff.Completed); n > 0 {
+		parts = append(parts, styles["priorityLow"].Render(fmt.Sprintf("✓%d completed", n)))
+	}
+	if n := len(diff.Reopened) + len(diff.Changed); n > 0 {
+		parts = append(parts, styles["priorityMedium"].Render(fmt.Sprintf("~%d changed", n)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// scrollWindow returns the range of rows to show so that cursor stays visible
+func scrollWindow(cursor, total, rows int) (int, int) {
+	start := 0
+	if cursor >= rows {
+		start = cursor - rows + 1
+	}
+	end := start + rows
+	if end > total {
+		end = total
+	}
+	return start, end
+}
@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spmfte/tuiodo/model"
+)
+
+// renderNotesEditor shows the multi-line editor for a task's notes
+func renderNotesEditor(m model.Model, styles map[string]lipgloss.Style, width int) string {
+	editor := m.NotesEditor
+
+	title := "Notes"
+	if idx := m.TaskIndexByID(editor.TaskID); idx >= 0 {
+		title += styles["inputHint"].Render(" for: " + cleanMetadata(m.Tasks[idx].Description))
+	}
+
+	// Leave room for the header, tabs, hints and status bar
+	rows := m.Height - 14
+	if rows < 5 {
+		rows = 5
+	}
+
+	form := []string{styles["inputPrompt"].Render(title), ""}
+	start, end := scrollWindow(editor.Row, len(editor.Lines), rows)
+	for i := start; i < end; i++ {
+		line := editor.Lines[i]
+		if i == editor.Row {
+			runes := []rune(line)
+			line = string(runes[:editor.Col]) + styles["inputCursor"].Render("▋") + string(runes[editor.Col:])
+		}
+		form = append(form, styles["input"].Render("  "+line))
+	}
+	form = append(form, "",
+		styles["inputHint"].Render("Ctrl+S to save, Esc to cancel • Enter for a new line • Arrows move the cursor"))
+
+	return styles["inputBox"].Render(strings.Join(form, "\n"))
+}
@@ -1,10 +1,14 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spmfte/tuiodo/model"
@@ -40,6 +44,16 @@ func View(m model.Model) string {
 		return renderHelpScreen(styles, containerWidth, m.Height)
 	}
 
+	// Conflicts with changes on disk take over the screen until resolved
+	if len(m.Conflicts) > 0 {
+		return renderConflictScreen(m, styles, containerWidth)
+	}
+
+	// Backup browser
+	if m.BackupsVisible && len(m.Backups) > 0 {
+		return renderBackupScreen(m, styles, containerWidth)
+	}
+
 	// Build the UI components
 	var appContent []string
 
@@ -52,8 +66,13 @@ func View(m model.Model) string {
 	// === INPUT FORM (when in input mode) ===
 	if m.InputMode || m.EditingTask {
 		appContent = append(appContent, renderInputForm(m, styles, containerWidth))
+	} else if m.NotesEditor != nil {
+		appContent = append(appContent, renderNotesEditor(m, styles, containerWidth))
 	} else {
 		// === TASKS SECTION (when not in input mode) ===
+		if m.SearchMode || m.Search != "" {
+			appContent = append(appContent, renderSearchBar(m, styles))
+		}
 		appContent = append(appContent, renderTaskList(m, styles, containerWidth))
 	}
 
@@ -86,9 +105,26 @@ func renderHeader(m model.Model, styles map[string]lipgloss.Style, width int) st
 	if m.CurrentFilter != "" {
 		filterLabel = styles["filterIndicator"].Render("Category: " + m.CurrentFilter)
 	}
+	if m.CompletedWindow != "" {
+		filterLabel += styles["filterIndicator"].Render("Completed " + m.CompletedWindow)
+	}
+	if m.Query != nil {
+		query := m.Query.Text
+		if len(query) > 30 {
+			query = query[:27] + "..."
+		}
+		filterLabel += styles["filterIndicator"].Render("Query: " + query)
+	}
+
+	// The file being edited, with its path if there's room
+	file := storage.ActiveFile()
+	fileLabel := styles["inputHint"].Render(" " + file.Name + ": " + displayPath(file.Path()))
+	if width-lipgloss.Width(title)-lipgloss.Width(versionBadge)-lipgloss.Width(filterLabel)-lipgloss.Width(fileLabel)-4 < 0 {
+		fileLabel = styles["inputHint"].Render(" " + file.Name)
+	}
 
 	// Assemble the title bar with correct spacing
-	emptySpace := width - lipgloss.Width(title) - lipgloss.Width(versionBadge) - lipgloss.Width(filterLabel) - 4
+	emptySpace := width - lipgloss.Width(title) - lipgloss.Width(versionBadge) - lipgloss.Width(fileLabel) - lipgloss.Width(filterLabel) - 4
 	if emptySpace < 0 {
 		emptySpace = 0
 	}
@@ -96,34 +132,71 @@ func renderHeader(m model.Model, styles map[string]lipgloss.Style, width int) st
 	titleBar.WriteString(title)
 	titleBar.WriteString(" ")
 	titleBar.WriteString(versionBadge)
+	titleBar.WriteString(fileLabel)
 	titleBar.WriteString(strings.Repeat(" ", emptySpace))
 	titleBar.WriteString(filterLabel)
 
 	return styles["titleBar"].Render(titleBar.String())
 }
 
+// renderDue formats a task's due date for the due column, coloured by how
+// soon it is due. A due time is shown instead of the year.
+func renderDue(task model.Task, styles map[string]lipgloss.Style, width int) string {
+	due, layout, ok := task.Due()
+	text := task.Metadata["due"]
+	if ok && layout == model.DateTimeLayout {
+		text = due.Format("01-02 15:04")
+	}
+	if len(text) > width {
+		text = text[:width]
+	}
+	text = fmt.Sprintf("%-*s", width, text)
+
+	// Only the colour of the priority styles, not their padding
+	switch task.DueStatus(time.Now()) {
+	case model.DueOverdue:
+		return lipgloss.NewStyle().Foreground(styles["priorityHigh"].GetForeground()).Bold(true).Render(text)
+	case model.DueToday, model.DueUpcoming:
+		return lipgloss.NewStyle().Foreground(styles["priorityMedium"].GetForeground()).Render(text)
+	}
+	return lipgloss.NewStyle().Foreground(styles["date"].GetForeground()).Render(text)
+}
+
+// displayPath shortens a path in the home directory to start with ~
+func displayPath(path string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil || homeDir == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(homeDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join("~", rel)
+	}
+	return path
+}
+
 // renderTabs creates the tab navigation bar
 func renderTabs(m model.Model, styles map[string]lipgloss.Style, width int) string {
-	tabs := []struct {
-		title string
-		view  model.TabView
-	}{
-		{"All", model.TabAll},
-		{"Pending", model.TabPending},
-		{"Completed", model.TabCompleted},
+	titles := map[model.TabView]string{
+		model.TabAll:       "All",
+		model.TabToday:     "Today",
+		model.TabOverdue:   "Overdue",
+		model.TabUpcoming:  "Upcoming",
+		model.TabPending:   "Pending",
+		model.TabWaiting:   "Waiting",
+		model.TabCompleted: "Completed",
 	}
 
 	var renderedTabs []string
 
 	// Render each tab with appropriate active/inactive styling
-	for _, tab := range tabs {
+	for _, view := range model.Tabs {
 		var tabStyle lipgloss.Style
-		if tab.view == m.CurrentView {
+		if view == m.CurrentView {
 			tabStyle = styles["tabActive"]
 		} else {
 			tabStyle = styles["tabInactive"]
 		}
-		renderedTabs = append(renderedTabs, tabStyle.Render(tab.title))
+		renderedTabs = append(renderedTabs, tabStyle.Render(titles[view]))
 	}
 
 	return lipgloss.NewStyle().
@@ -135,14 +208,33 @@ func renderTabs(m model.Model, styles map[string]lipgloss.Style, width int) stri
 // renderInputForm creates the input form for adding/editing tasks
 func renderInputForm(m model.Model, styles map[string]lipgloss.Style, width int) string {
 	var title string
-	if m.EditingTask {
+	if m.SnoozeID != "" {
+		title = "Snooze Until"
+	} else if m.EditingQuery {
+		title = "Filter"
+	} else if m.BulkPrompt == "category" {
+		title = fmt.Sprintf("Move %d Marked Task(s) to Category", len(m.MarkedIDs()))
+	} else if m.BulkPrompt == "tags" {
+		title = fmt.Sprintf("Tag %d Marked Task(s)", len(m.MarkedIDs()))
+	} else if m.EditingTask {
 		title = "Edit Task"
+	} else if m.InputParentID != "" {
+		title = "New Subtask"
 	} else {
 		title = "New Task"
 	}
 
 	prompt := styles["inputPrompt"].Render(title)
 	hint := styles["inputHint"].Render(" (Format: Category: Task description)")
+	if idx := m.TaskIndexByID(m.InputParentID); idx >= 0 && !m.EditingTask {
+		hint = styles["inputHint"].Render(" of: " + cleanMetadata(m.Tasks[idx].Description))
+	}
+	if idx := m.TaskIndexByID(m.SnoozeID); idx >= 0 {
+		hint = styles["inputHint"].Render(" " + cleanMetadata(m.Tasks[idx].Description))
+	}
+	if m.BulkPrompt != "" || m.EditingQuery {
+		hint = ""
+	}
 	cursor := styles["inputCursor"].Render("▋")
 
 	// Split input into before and after cursor
@@ -154,12 +246,111 @@ func renderInputForm(m model.Model, styles map[string]lipgloss.Style, width int)
 		"",
 		styles["input"].Render("→ " + inputBefore + cursor + inputAfter),
 		"",
-		styles["inputHint"].Render("Press Enter to save, Esc to cancel • Use ←→ to move cursor • Add @priority:high/medium/low/critical"),
+		styles["inputHint"].Render("Press Enter to save, Esc to cancel • Use ←→ to move cursor • Add @priority:high/medium/low/critical, @due:fri") +
+			renderDatePreviews(m.Input, styles),
+	}
+	if m.EditingQuery {
+		inputForm[len(inputForm)-1] = styles["inputHint"].Render("Press Enter to filter, Esc to cancel • e.g. priority>=high tag:backend due<7d -status:blocked \"login page\" • AND, OR, NOT, ( )")
+		inputForm = append(inputForm[:3], append([]string{renderQueryCheck(m, styles)}, inputForm[3:]...)...)
+	}
+	switch m.BulkPrompt {
+	case "category":
+		inputForm[len(inputForm)-1] = styles["inputHint"].Render("Press Enter to move, Esc to cancel • Tab completes a category • Leave empty to clear it")
+	case "tags":
+		inputForm[len(inputForm)-1] = styles["inputHint"].Render("Press Enter to apply, Esc to cancel • e.g. +urgent -later adds urgent and removes later")
+	}
+	if m.SnoozeID != "" {
+		inputForm[len(inputForm)-1] = styles["inputHint"].Render("Press Enter to snooze, Esc to cancel • e.g. fri, +3d, next-week, 2025-03-14") +
+			renderDatePreview("start", m.Input, styles)
 	}
 
 	return styles["inputBox"].Render(strings.Join(inputForm, "\n"))
 }
 
+// renderSearchBar shows the search above the task list, with a cursor
+// while it is typed, and how many tasks match it
+func renderSearchBar(m model.Model, styles map[string]lipgloss.Style) string {
+	search := m.Search
+	if m.SearchMode {
+		search += styles["inputCursor"].Render("▋")
+	}
+	matches := styles["inputHint"].Render(fmt.Sprintf("  %d match(es)", len(m.GetFilteredTasks())))
+	if !m.SearchMode {
+		matches += styles["inputHint"].Render(" • n/N next/previous • / edit • esc clear")
+	}
+	return styles["inputPrompt"].Render("/ ") + styles["input"].Render(search) + matches
+}
+
+// renderHighlighted renders text in style, with the letters matched by the
+// search underlined in the cursor colour
+func renderHighlighted(text, search string, style lipgloss.Style, styles map[string]lipgloss.Style) string {
+	highlights := model.SearchHighlights(search, text)
+	if len(highlights) == 0 {
+		return style.Render(text)
+	}
+
+	highlight := style.Copy().Foreground(styles["cursor"].GetForeground()).Bold(true).Underline(true)
+	var b strings.Builder
+	for i, r := range []rune(text) {
+		if highlights[i] {
+			b.WriteString(highlight.Render(string(r)))
+		} else {
+			b.WriteString(style.Render(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// renderQueryCheck shows, below the query bar, where the query typed so far
+// can't be parsed, or how many tasks it matches
+func renderQueryCheck(m model.Model, styles map[string]lipgloss.Style) string {
+	q, err := model.ParseQuery(m.Input)
+	var queryErr *model.QueryError
+	if errors.As(err, &queryErr) {
+		// Point at the problem, allowing for the arrow and the cursor
+		column := utf8.RuneCountInString(m.Input[:queryErr.Offset]) + 2
+		if queryErr.Offset >= m.InputCursor {
+			column++
+		}
+		errorStyle := lipgloss.NewStyle().Foreground(styles["priorityHigh"].GetForeground())
+		return styles["input"].Render(strings.Repeat(" ", column) + errorStyle.Render("^ "+queryErr.Message))
+	}
+
+	preview := m
+	preview.Query = q
+	return styles["inputHint"].Render(fmt.Sprintf("%d matching task(s)", len(preview.GetFilteredTasks())))
+}
+
+// renderDatePreviews shows the dates the @due and @start tokens in the
+// input resolve to, so forms like @due:fri can be checked before saving
+func renderDatePreviews(input string, styles map[string]lipgloss.Style) string {
+	_, fields := model.ParseMetadataFromText(input)
+	var previews string
+	for _, field := range fields {
+		if model.IsDateKey(field.Key) {
+			previews += renderDatePreview(field.Key, field.Value, styles)
+		}
+	}
+	return previews
+}
+
+// renderDatePreview shows the date a value typed for the key resolves to
+func renderDatePreview(key, value string, styles map[string]lipgloss.Style) string {
+	if strings.TrimSpace(value) == "" {
+		return ""
+	}
+	resolved, err := model.ResolveDue(value, time.Now())
+	if err != nil {
+		return lipgloss.NewStyle().Foreground(styles["priorityHigh"].GetForeground()).Render(" • " + key + ": ?")
+	}
+	date, layout, _ := model.Task{Metadata: map[string]string{"due": resolved}}.Due()
+	text := date.Format("Mon 2006-01-02")
+	if layout == model.DateTimeLayout {
+		text = date.Format("Mon 2006-01-02 15:04")
+	}
+	return lipgloss.NewStyle().Foreground(styles["priorityLow"].GetForeground()).Render(" • " + key + ": " + text)
+}
+
 // renderTaskList creates the list of tasks
 func renderTaskList(m model.Model, styles map[string]lipgloss.Style, width int) string {
 	// Get filtered and paginated tasks
@@ -171,6 +362,25 @@ func renderTaskList(m model.Model, styles map[string]lipgloss.Style, width int)
 		if m.CurrentFilter != "" {
 			emptyText = "No tasks in category '" + m.CurrentFilter + "'"
 		}
+		switch m.CurrentView {
+		case model.TabToday:
+			emptyText = "Nothing due today"
+		case model.TabOverdue:
+			emptyText = "Nothing overdue"
+		case model.TabUpcoming:
+			emptyText = fmt.Sprintf("Nothing due in the next %d days", model.UpcomingDays)
+		case model.TabWaiting:
+			emptyText = "Nothing waiting for its @start date (press 'Z' to snooze a task)"
+		}
+		if m.CompletedWindow != "" {
+			emptyText = "No tasks completed " + m.CompletedWindow + " (press 'w' to change)"
+		}
+		if m.Query != nil {
+			emptyText = "No tasks match " + m.Query.Text + " (press 'f' to change it, esc to clear it)"
+		}
+		if m.Search != "" {
+			emptyText = fmt.Sprintf("No tasks match the search %q (esc clears it)", m.Search)
+		}
 		return styles["emptyMessage"].Render(emptyText)
 	}
 
@@ -195,17 +405,35 @@ func renderTaskList(m model.Model, styles map[string]lipgloss.Style, width int)
 	categoryWidth := max(minCategoryWidth, contentWidth*20/100)                       // 20% for category
 	taskWidth := max(minTitleWidth, contentWidth-categoryWidth-dateWidth-(spacing*2)) // Remaining space for task
 
+	// With tasks from several files, a source column says which file each
+	// one belongs to
+	sourceWidth := 0
+	sourceHeader := ""
+	if m.HasSources() {
+		sourceWidth = max(minCategoryWidth, contentWidth*15/100)
+		taskWidth = max(minTitleWidth, taskWidth-sourceWidth-spacing)
+		sourceHeader = fmt.Sprintf("%-*s", sourceWidth+spacing, "SOURCE")
+	}
+
+	// Due dates get their own column
+	dueWidth := 11
+	taskWidth = max(minTitleWidth, taskWidth-dueWidth-spacing)
+
 	// Create the header with proper spacing - we don't need headerFormat anymore
 	taskHeader := styles["taskHeader"].Copy().
 		MarginLeft(3).
 		Bold(true).
-		Render(fmt.Sprintf("TASK%s%s%sCREATED",
+		Render(fmt.Sprintf("TASK%s%s%s%s%sCREATED",
 			strings.Repeat(" ", taskWidth-4),
+			sourceHeader,
 			"CATEGORY",
-			strings.Repeat(" ", categoryWidth+spacing-8)))
+			strings.Repeat(" ", categoryWidth+spacing-8),
+			fmt.Sprintf("%-*s", dueWidth+spacing, "DUE")))
 
 	taskList = append(taskList, taskHeader)
 
+	depths := m.TreeDepths()
+
 	// Render each task
 	for i, task := range visibleTasks {
 		var taskRow strings.Builder
@@ -271,12 +499,32 @@ func renderTaskList(m model.Model, styles map[string]lipgloss.Style, width int)
 			taskStyle = styles["taskArchived"]
 		} else if task.Done {
 			taskStyle = styles["taskDone"]
+		} else if m.IsBlocked(task.ID) {
+			taskStyle = styles["inputHint"]
 		} else {
 			taskStyle = styles["taskPending"]
 		}
 
 		// Clean description and truncate if needed
 		description := cleanMetadata(task.Description)
+		if m.IsBlocked(task.ID) {
+			description = "⊘ " + description
+		}
+		if m.IsMarked(task.ID) {
+			description = "● " + description
+		}
+
+		// Subtasks are indented below their parent, and parents show a fold
+		// marker and how many of their subtasks are done
+		treePrefix := strings.Repeat("  ", depths[task.ID])
+		if done, total := m.SubtaskProgress(task.ID); total > 0 {
+			if m.IsCollapsed(task.ID) {
+				treePrefix += "▸ "
+			} else {
+				treePrefix += "▾ "
+			}
+			description = fmt.Sprintf("%s [%d/%d]", description, done, total)
+		}
 
 		// Calculate category length for potential truncation
 		categoryStrLen := len(task.Category)
@@ -285,13 +533,26 @@ func renderTaskList(m model.Model, styles map[string]lipgloss.Style, width int)
 		// If category is longer than its allocated space, take space from task
 		adjustedTaskWidth = max(minTitleWidth, adjustedTaskWidth-extraCategoryLen)
 
-		if len(description) > adjustedTaskWidth {
-			description = description[:adjustedTaskWidth-3] + "..."
+		descriptionWidth := max(4, adjustedTaskWidth-lipgloss.Width(treePrefix))
+		if len(description) > descriptionWidth {
+			description = description[:descriptionWidth-3] + "..."
 		}
 
-		// Pad the description to its adjusted width
-		paddedDesc := fmt.Sprintf("%-*s", adjustedTaskWidth, description)
-		taskRow.WriteString(taskStyle.Render(paddedDesc))
+		// Pad the description to its adjusted width, highlighting what the
+		// search matched
+		padding := strings.Repeat(" ", max(0, descriptionWidth-utf8.RuneCountInString(description)))
+		taskRow.WriteString(taskStyle.Render(treePrefix))
+		taskRow.WriteString(renderHighlighted(description, m.Search, taskStyle, styles))
+		taskRow.WriteString(taskStyle.Render(padding))
+
+		if sourceWidth > 0 {
+			source := task.Source
+			if len(source) > sourceWidth {
+				// Keep the end of the path, which names the package
+				source = "..." + source[len(source)-sourceWidth+3:]
+			}
+			taskRow.WriteString(styles["inputHint"].Render(fmt.Sprintf("%-*s", sourceWidth+spacing, source)))
+		}
 
 		// Category with appropriate width
 		category := task.Category
@@ -327,14 +588,21 @@ func renderTaskList(m model.Model, styles map[string]lipgloss.Style, width int)
 		// Add spacing between category and date
 		taskRow.WriteString(strings.Repeat(" ", spacing))
 
-		// Creation date (local time, date only)
-		createdDate := task.CreatedAt.Local().Format("2006-01-02")
+		// Due date, red once overdue and amber while due within a week
+		taskRow.WriteString(renderDue(task, styles, dueWidth))
+		taskRow.WriteString(strings.Repeat(" ", spacing))
+
+		// Creation date (local time, date only), blank if the file doesn't record it
+		createdDate := strings.Repeat(" ", 10)
+		if !task.CreatedAt.IsZero() {
+			createdDate = task.CreatedAt.Local().Format("2006-01-02")
+		}
 		taskRow.WriteString(styles["date"].Render(createdDate))
 
 		taskList = append(taskList, taskRow.String())
 
 		// If this task is expanded, show its full details
-		if m.TaskExpanded && i == m.ExpandedTaskIdx {
+		if m.TaskExpanded && task.ID == m.ExpandedTaskID {
 			// Create a cleaner expanded view without blocks or borders
 			expandedDetails := []string{
 				"",
@@ -343,12 +611,27 @@ func renderTaskList(m model.Model, styles map[string]lipgloss.Style, width int)
 				"",
 			}
 
-			// Add metadata in a cleaner format
-			metaInfo := extractMetadata(task.Description)
+			// Notes, as written below the task in the file
+			if task.Notes != "" {
+				expandedDetails = append(expandedDetails, styles["secondary"].Copy().Bold(true).Render("Notes:"))
+				for _, line := range strings.Split(task.Notes, "\n") {
+					expandedDetails = append(expandedDetails, styles["taskPending"].Render("  "+line))
+				}
+				expandedDetails = append(expandedDetails, "")
+			}
 
 			// Display metadata in a cleaner two-column format
 			infoLayout := [][]string{
-				{styles["taskHeader"].Copy().Render("Created:"), styles["inputHint"].Render(task.CreatedAt.Local().Format("2006-01-02 15:04:05"))},
+				{styles["taskHeader"].Copy().Render("ID:"), styles["inputHint"].Render(task.ID)},
+			}
+			if task.Source != "" {
+				infoLayout = append(infoLayout, []string{styles["taskHeader"].Copy().Render("Source:"), styles["inputHint"].Render(task.Source)})
+			}
+			if !task.CreatedAt.IsZero() {
+				infoLayout = append(infoLayout, []string{styles["taskHeader"].Copy().Render("Created:"), styles["inputHint"].Render(task.CreatedAt.Local().Format("2006-01-02 15:04:05"))})
+			}
+			if task.Done && !task.CompletedAt.IsZero() {
+				infoLayout = append(infoLayout, []string{styles["taskHeader"].Copy().Render("Completed:"), styles["inputHint"].Render(task.CompletedAt.Local().Format("2006-01-02 15:04:05"))})
 			}
 
 			// Add priority if present and task is not completed
@@ -390,24 +673,63 @@ func renderTaskList(m model.Model, styles map[string]lipgloss.Style, width int)
 				infoLayout = append(infoLayout, []string{styles["taskHeader"].Copy().Render("Category:"), categoryStyle.Render(task.Category)})
 			}
 
+			if r, ok := task.Recurrence(); ok {
+				infoLayout = append(infoLayout, []string{styles["taskHeader"].Copy().Render("Repeats:"), styles["inputHint"].Render(r.String())})
+			}
+
+			if spent := renderTimeSpent(task, styles); spent != "" {
+				infoLayout = append(infoLayout, []string{styles["taskHeader"].Copy().Render("Time spent:"), spent})
+			}
+
+			if blockers := task.Blockers(); len(blockers) > 0 {
+				infoLayout = append(infoLayout, []string{styles["taskHeader"].Copy().Render("Blocked by:"), renderDependencies(m, blockers, styles)})
+			}
+			if dependants := m.Dependants(task.ID); len(dependants) > 0 {
+				ids := make([]string, len(dependants))
+				for i, dependant := range dependants {
+					ids[i] = dependant.ID
+				}
+				infoLayout = append(infoLayout, []string{styles["taskHeader"].Copy().Render("Needed by:"), renderDependencies(m, ids, styles)})
+			}
+			for _, cycle := range m.DependencyCycles() {
+				if slices.Contains(cycle, task.ID) {
+					cycleStyle := lipgloss.NewStyle().Foreground(styles["priorityHigh"].GetForeground())
+					infoLayout = append(infoLayout, []string{styles["taskHeader"].Copy().Render("Cycle:"), cycleStyle.Render(strings.Join(cycle, " → "))})
+					break
+				}
+			}
+
 			// Format the two-column layout with consistent spacing
 			for _, row := range infoLayout {
 				expandedDetails = append(expandedDetails, fmt.Sprintf("  %-12s %s", row[0], row[1]))
 			}
 
-			// Add metadata tags if present
-			if len(metaInfo) > 0 {
+			// Add metadata tags if present, in the order they were written
+			if metaKeys := task.MetadataKeys(); len(metaKeys) > 0 {
 				expandedDetails = append(expandedDetails, "")
 				expandedDetails = append(expandedDetails, styles["secondary"].Copy().Bold(true).Render("Metadata Tags:"))
 
-				for key, value := range metaInfo {
-					expandedDetails = append(expandedDetails, fmt.Sprintf("  @%-10s %s", key+":", value))
+				for _, key := range metaKeys {
+					expandedDetails = append(expandedDetails, fmt.Sprintf("  @%-10s %s", key+":", task.Metadata[key]))
+				}
+			}
+
+			// The task's history from the activity log, most recent last
+			if history := m.ExpandedHistory; len(history) > 0 {
+				expandedDetails = append(expandedDetails, "")
+				expandedDetails = append(expandedDetails, styles["secondary"].Copy().Bold(true).Render("History:"))
+				if len(history) > maxHistoryEvents {
+					expandedDetails = append(expandedDetails, styles["inputHint"].Render(fmt.Sprintf("  %d earlier change(s); see tuiodo log --task %s", len(history)-maxHistoryEvents, task.ID)))
+					history = history[len(history)-maxHistoryEvents:]
+				}
+				for _, event := range history {
+					expandedDetails = append(expandedDetails, styles["inputHint"].Render("  "+renderEvent(event)))
 				}
 			}
 
 			// Add a help hint at the bottom
 			expandedDetails = append(expandedDetails, "")
-			expandedDetails = append(expandedDetails, styles["inputHint"].Italic(true).Render("  Press 'x' to collapse"))
+			expandedDetails = append(expandedDetails, styles["inputHint"].Italic(true).Render("  Press 'x' to collapse, 'E' to edit notes"))
 
 			// Render without additional styling that might cause formatting issues
 			expandedView := strings.Join(expandedDetails, "\n")
@@ -432,33 +754,79 @@ func renderTaskList(m model.Model, styles map[string]lipgloss.Style, width int)
 
 // cleanMetadata removes metadata tags from task description
 func cleanMetadata(description string) string {
-	// Remove @created tag with timestamp
-	createdPattern := regexp.MustCompile(`\s*@created:\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z`)
-	description = createdPattern.ReplaceAllString(description, "")
-
-	// Remove @priority tag
-	priorityPattern := regexp.MustCompile(`\s*@priority:(high|medium|low|critical)`)
-	description = priorityPattern.ReplaceAllString(description, "")
+	cleaned, _ := model.ParseMetadataFromText(description)
+	return cleaned
+}
 
-	// Remove any trailing/leading whitespace
-	return strings.TrimSpace(description)
+// renderDependencies lists the tasks with the given IDs, one per line, with
+// a check mark on those that are done. IDs not in the list are shown as
+// unknown.
+func renderDependencies(m model.Model, ids []string, styles map[string]lipgloss.Style) string {
+	lines := make([]string, len(ids))
+	for i, id := range ids {
+		idx := m.TaskIndexByID(id)
+		switch {
+		case idx < 0:
+			lines[i] = styles["inputHint"].Render(id + " (unknown task)")
+		case m.Tasks[idx].Done:
+			lines[i] = styles["taskDone"].Render("✓ " + id + " " + cleanMetadata(m.Tasks[idx].Description))
+		default:
+			lines[i] = styles["taskPending"].Render("○ " + id + " " + cleanMetadata(m.Tasks[idx].Description))
+		}
+	}
+	return strings.Join(lines, "\n"+strings.Repeat(" ", 15))
 }
 
-// extractMetadata extracts metadata tags from a task description
-func extractMetadata(description string) map[string]string {
-	metadata := make(map[string]string)
+// maxHistoryEvents is how many changes the expanded view shows from a task's
+// history
+const maxHistoryEvents = 8
+
+// renderEvent describes a change to a task from the activity log
+func renderEvent(event model.Event) string {
+	action := event.Action
+	if event.Action == model.ActionPriority {
+		from, to := event.From, event.To
+		if from == "" {
+			from = "none"
+		}
+		if to == "" {
+			to = "none"
+		}
+		action = fmt.Sprintf("%s %s → %s", action, from, to)
+	}
+	text := event.Time.Local().Format("2006-01-02 15:04") + "  " + action
+	if event.Actor != "" {
+		text += " by " + event.Actor
+	}
+	return text
+}
 
-	// Find all @tag:value patterns
-	metadataPattern := regexp.MustCompile(`@([a-zA-Z0-9_]+):([^\s@]+)`)
-	matches := metadataPattern.FindAllStringSubmatch(description, -1)
+// renderTimeSpent shows the time logged against a task and its estimate,
+// red once the estimate is used up. It is empty if neither is set.
+func renderTimeSpent(task model.Task, styles map[string]lipgloss.Style) string {
+	now := time.Now()
+	spent := task.TimeSpent(now)
+	estimate, hasEstimate := task.Estimate()
+	_, running := task.TimerStarted()
+	if spent == 0 && !hasEstimate && !running {
+		return ""
+	}
 
-	for _, match := range matches {
-		if len(match) == 3 {
-			metadata[match[1]] = match[2]
+	text := model.FormatEffort(spent)
+	color := styles["inputHint"].GetForeground()
+	if hasEstimate {
+		text += fmt.Sprintf(" of %s estimated", model.FormatEffort(estimate))
+		if estimate > 0 {
+			text += fmt.Sprintf(" (%.0f%%)", float64(spent)/float64(estimate)*100)
+		}
+		if spent > estimate {
+			color = styles["priorityHigh"].GetForeground()
 		}
 	}
-
-	return metadata
+	if running {
+		text += " • timer running"
+	}
+	return lipgloss.NewStyle().Foreground(color).Render(text)
 }
 
 // renderStatusBar creates the status bar at the bottom
@@ -521,6 +889,9 @@ func renderStatusBar(m model.Model, styles map[string]lipgloss.Style, width int)
 	// Add file info
 	filePath := storage.GetStoragePath()
 	fileName := filepath.Base(filePath)
+	if m.Dirty {
+		fileName += " [modified]"
+	}
 
 	// Format right side
 	rightSide := fmt.Sprintf(
@@ -531,6 +902,24 @@ func renderStatusBar(m model.Model, styles map[string]lipgloss.Style, width int)
 		styles["inputHint"].Render(fileName),
 	)
 
+	// Show how many tasks are marked for bulk actions
+	if n := len(m.MarkedIDs()); n > 0 {
+		markStyle := lipgloss.NewStyle().Foreground(styles["cursor"].GetForeground()).Bold(true)
+		rightSide = markStyle.Render(fmt.Sprintf("● %d marked", n)) + " • " + rightSide
+	}
+
+	// Show the running timer, if any, before the stats
+	if task, ok := m.RunningTimer(); ok {
+		start, _ := task.TimerStarted()
+		timerStyle := lipgloss.NewStyle().Foreground(styles["priorityMedium"].GetForeground())
+		description := cleanMetadata(task.Description)
+		if len(description) > 20 {
+			description = description[:17] + "..."
+		}
+		timer := fmt.Sprintf("⏱ %s %s", model.FormatEffort(time.Since(start)), description)
+		rightSide = timerStyle.Render(timer) + " • " + rightSide
+	}
+
 	// Determine spacing
 	spacerWidth := width - lipgloss.Width(leftSide) - lipgloss.Width(rightSide) - 2
 	if spacerWidth < 1 {
@@ -574,26 +963,51 @@ func renderHelpScreen(styles map[string]lipgloss.Style, width int, height int) s
 		fmt.Sprintf("%s : Move cursor up", keyStyle.Render("j/k, ↑/↓")),
 		fmt.Sprintf("%s : Navigate between tabs", keyStyle.Render("tab, t")),
 		fmt.Sprintf("%s : Next/previous page", keyStyle.Render("n/b, →/←")),
+		fmt.Sprintf("%s : Search descriptions, categories, tags and notes as you type", keyStyle.Render("/")),
+		fmt.Sprintf("%s : Next/previous search match (while searching)", keyStyle.Render("n/N")),
 		"",
 		sectionStyle.Render("TASK MANAGEMENT"),
-		fmt.Sprintf("%s : Add new task", keyStyle.Render("a")),
+		fmt.Sprintf("%s : Add new task (@due:fri, +3d, next-month, tomorrowT09:00)", keyStyle.Render("a")),
+		fmt.Sprintf("%s : Add subtask to current task", keyStyle.Render("o")),
+		fmt.Sprintf("%s : Fold/unfold subtasks", keyStyle.Render("z")),
 		fmt.Sprintf("%s : Edit current task", keyStyle.Render("e")),
 		fmt.Sprintf("%s : Delete task (press twice to confirm)", keyStyle.Render("d")),
-		fmt.Sprintf("%s : Undo last deletion", keyStyle.Render("u")),
-		fmt.Sprintf("%s : Toggle task completion", keyStyle.Render("space, enter")),
-		fmt.Sprintf("%s : Expand/collapse task details", keyStyle.Render("x")),
+		fmt.Sprintf("%s : Undo last change", keyStyle.Render("u")),
+		fmt.Sprintf("%s : Redo undone change", keyStyle.Render("ctrl+r")),
+		fmt.Sprintf("%s : Toggle task completion (@every tasks repeat, @blocked-by tasks ask first)", keyStyle.Render("space, enter")),
+		fmt.Sprintf("%s : Expand/collapse task details and history", keyStyle.Render("x")),
+		fmt.Sprintf("%s : Edit task notes (Ctrl+S saves, Esc cancels)", keyStyle.Render("E")),
 		fmt.Sprintf("%s : Archive current task", keyStyle.Render("A")),
+		fmt.Sprintf("%s : Snooze: hide until tomorrow, next week or a date (@start)", keyStyle.Render("Z")),
+		fmt.Sprintf("%s : Start/stop the timer (@estimate sets the expected time)", keyStyle.Render("T")),
 		fmt.Sprintf("%s : Unarchive current task", keyStyle.Render("U")),
 		fmt.Sprintf("%s : Cycle priority (none/low/medium/high/critical)", keyStyle.Render("p")),
 		"",
+		sectionStyle.Render("BULK ACTIONS"),
+		fmt.Sprintf("%s : Mark/unmark current task and move down", keyStyle.Render("v")),
+		fmt.Sprintf("%s : Mark every task from the last marked to the current one", keyStyle.Render("V")),
+		fmt.Sprintf("%s : Mark/unmark every task in the current view", keyStyle.Render("ctrl+a")),
+		fmt.Sprintf("%s : With tasks marked: complete, archive, unarchive, delete", keyStyle.Render("space, A, U, d")),
+		fmt.Sprintf("%s : With tasks marked: set priority (c/h/m/l/n)", keyStyle.Render("p")),
+		fmt.Sprintf("%s : With tasks marked: move to a category, +add/-remove tags", keyStyle.Render("m, #")),
+		fmt.Sprintf("%s : Clear marks", keyStyle.Render("esc")),
+		"",
 		sectionStyle.Render("SORTING & FILTERING"),
 		fmt.Sprintf("%s : Sort by priority", keyStyle.Render("s")),
 		fmt.Sprintf("%s : Sort by creation date", keyStyle.Render("S")),
 		fmt.Sprintf("%s : Sort by category", keyStyle.Render("C")),
+		fmt.Sprintf("%s : Sort by due date", keyStyle.Render("D")),
 		fmt.Sprintf("%s : Cycle through categories", keyStyle.Render("c")),
-		fmt.Sprintf("%s : Switch between views (All/Pending/Completed)", keyStyle.Render("tab, t")),
+		fmt.Sprintf("%s : Filter with a query, e.g. priority>=high tag:api due<7d -status:blocked", keyStyle.Render("f")),
+		fmt.Sprintf("%s : Clear the search, then the query", keyStyle.Render("esc")),
+		fmt.Sprintf("%s : Show tasks completed today/this week/last 7 or 30 days", keyStyle.Render("w")),
+		fmt.Sprintf("%s : Switch between views (All/Today/Overdue/Upcoming/Pending/Completed)", keyStyle.Render("tab, t")),
 		"",
 		sectionStyle.Render("OTHER"),
+		fmt.Sprintf("%s : Switch between the project and global task files", keyStyle.Render("F")),
+		fmt.Sprintf("%s : Move current task to the other file", keyStyle.Render("M")),
+		fmt.Sprintf("%s : Browse backups (diff, restore, restore single tasks)", keyStyle.Render("B")),
+		fmt.Sprintf("%s : Save now (when auto-save is off)", keyStyle.Render("Ctrl+S")),
 		fmt.Sprintf("%s : Show/hide this help", keyStyle.Render("?, h, F1")),
 		fmt.Sprintf("%s : Quit application", keyStyle.Render("q, Ctrl+C")),
 		"",
@@ -603,6 +1017,48 @@ func renderHelpScreen(styles map[string]lipgloss.Style, width int, height int) s
 	return styles["helpBox"].Copy().Width(width).Render(strings.Join(helpContent, "\n"))
 }
 
+// renderConflictScreen shows the first unresolved conflict between our
+// changes and the ones made to the file on disk
+func renderConflictScreen(m model.Model, styles map[string]lipgloss.Style, width int) string {
+	c := m.Conflicts[0]
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(styles["title"].GetForeground()).
+		Padding(0, 1).
+		MarginBottom(1)
+
+	labelStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(styles["secondary"].GetForeground())
+
+	keyStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(styles["helpCommand"].GetForeground())
+
+	field := c.Field
+	if field == "" {
+		field = "task"
+	}
+
+	content := []string{
+		titleStyle.Render(fmt.Sprintf("CONFLICT %d OF %d", 1, len(m.Conflicts))),
+		fmt.Sprintf("%s changed on disk while you had unsaved changes.", filepath.Base(storage.GetStoragePath())),
+		"",
+		fmt.Sprintf("%s %s", labelStyle.Render("Task:  "), c.Description),
+		fmt.Sprintf("%s %s", labelStyle.Render("Field: "), field),
+		"",
+		fmt.Sprintf("%s %s", labelStyle.Render("Was:   "), c.Base),
+		fmt.Sprintf("%s %s", labelStyle.Render("Mine:  "), styles["priorityLow"].Render(c.Ours)),
+		fmt.Sprintf("%s %s", labelStyle.Render("Disk:  "), styles["priorityMedium"].Render(c.Theirs)),
+		"",
+		fmt.Sprintf("%s : Keep mine    %s : Take the version on disk", keyStyle.Render("m"), keyStyle.Render("t")),
+		fmt.Sprintf("%s : Keep mine for all    %s : Take disk for all", keyStyle.Render("M"), keyStyle.Render("T")),
+	}
+
+	return styles["helpBox"].Copy().Width(width).Render(strings.Join(content, "\n"))
+}
+
 // max helper function
 func max(a, b int) int {
 	if a > b {